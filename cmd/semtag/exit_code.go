@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/google-internal/semtag/pkg/semtag"
+)
+
+// Exit codes, documented in the README. Scripts rely on them, so existing
+// values must not change.
+const (
+	exitError            = 1
+	exitNotRepository    = 2
+	exitNoTagsFound      = 3
+	exitTagPatternError  = 4
+	exitTagParseError    = 5
+	exitShallowRepo      = 6
+	exitGitMissing       = 7
+	exitGitCommandFailed = 8
+	exitNoReleaseNeeded  = 10
+	exitMaintenanceLine  = 11
+	exitInvalidVersion   = 12
+	exitPolicyViolation  = 13
+	exitAPIBumpTooLow    = 14
+)
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	var (
+		patternErr *semtag.TagPatternError
+		parseErr   *semtag.TagParseError
+		gitErr     *semtag.GitCommandError
+		lineErr    *semtag.MaintenanceError
+		invalidErr *semtag.ValidationError
+		policyErr  *semtag.PolicyError
+		apiErr     *semtag.APIBumpError
+	)
+
+	switch {
+	case errors.Is(err, semtag.ErrNoReleaseNeeded):
+		return exitNoReleaseNeeded
+	case errors.As(err, &lineErr):
+		return exitMaintenanceLine
+	case errors.As(err, &invalidErr):
+		return exitInvalidVersion
+	case errors.As(err, &policyErr):
+		return exitPolicyViolation
+	case errors.As(err, &apiErr):
+		return exitAPIBumpTooLow
+	case errors.Is(err, semtag.ErrNotRepository):
+		return exitNotRepository
+	case errors.Is(err, semtag.ErrNoTagsFound):
+		return exitNoTagsFound
+	case errors.As(err, &patternErr):
+		return exitTagPatternError
+	case errors.As(err, &parseErr):
+		return exitTagParseError
+	case errors.Is(err, semtag.ErrShallowRepository):
+		return exitShallowRepo
+	case errors.Is(err, semtag.ErrGitMissing):
+		return exitGitMissing
+	case errors.As(err, &gitErr):
+		return exitGitCommandFailed
+	}
+	return exitError
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google-internal/semtag/pkg/semtag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	for expected, err := range map[int]error{
+		exitError:            errors.New("boom"),
+		exitNotRepository:    semtag.ErrNotRepository,
+		exitNoTagsFound:      semtag.ErrNoTagsFound,
+		exitTagPatternError:  &semtag.TagPatternError{Pattern: "v*", Kind: "tags"},
+		exitTagParseError:    fmt.Errorf("wrapped: %w", &semtag.TagParseError{Tag: "foo"}),
+		exitShallowRepo:      fmt.Errorf("%w: use --unshallow", semtag.ErrShallowRepository),
+		exitGitMissing:       semtag.ErrGitMissing,
+		exitGitCommandFailed: &semtag.GitCommandError{Args: []string{"log"}, ExitCode: 128},
+		exitNoReleaseNeeded:  semtag.ErrNoReleaseNeeded,
+		exitMaintenanceLine:  &semtag.MaintenanceError{Branch: "release/1.x", Line: "1.x"},
+		exitPolicyViolation:  &semtag.PolicyError{Bump: semtag.BumpMajor, Reason: "the maximum bump is minor"},
+		exitAPIBumpTooLow:    &semtag.APIBumpError{Bump: semtag.BumpPatch, Required: semtag.BumpMajor},
+		exitInvalidVersion:   &semtag.ValidationError{Tag: "v1.2.3", Problems: []semtag.Problem{{Message: "tag v1.2.3 already exists"}}},
+	} {
+		t.Run(err.Error(), func(t *testing.T) {
+			require.Equal(t, expected, exitCode(err))
+		})
+	}
+
+	t.Run("already reported", func(t *testing.T) {
+		require.Equal(t, exitNoReleaseNeeded, exitCode(reportedError{semtag.ErrNoReleaseNeeded}))
+	})
+
+	t.Run("git outside of a repository", func(t *testing.T) {
+		err := &semtag.GitCommandError{
+			Args:     []string{"tag"},
+			Stderr:   "fatal: not a git repository (or any of the parent directories): .git",
+			ExitCode: 128,
+		}
+		require.Equal(t, exitNotRepository, exitCode(err))
+	})
+}
@@ -1,28 +1,169 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/alecthomas/kong"
 
-	"github.com/google-internal/semtag/internal/git"
+	"github.com/google-internal/semtag/pkg/forge"
+	"github.com/google-internal/semtag/pkg/semtag"
 )
 
 type cli struct {
-	Next    nextCommand    `cmd:"next" help:"Calculate the next semantic version based on commits and branch" default:"1"`
-	Current currentCommand `cmd:"current" help:"Get the highest version tag reachable from the current branch"`
+	Next          nextCommand          `cmd:"next" help:"Calculate the next semantic version based on commits and branch" default:"1"`
+	Current       currentCommand       `cmd:"current" help:"Get the highest version tag reachable from the current branch"`
+	ShouldRelease shouldReleaseCommand `cmd:"should-release" help:"Exit with status 0 if commits warrant a new release, 10 otherwise"`
+	Notes         notesCommand         `cmd:"notes" help:"Render the release notes of the next version"`
+	Changelog     changelogCommand     `cmd:"changelog" help:"Move the Unreleased changes of a Keep a Changelog file under the next version"`
+	Release       releaseCommand       `cmd:"release" help:"Manage releases on the forge hosting the repository"`
+	Explain       explainCommand       `cmd:"explain" help:"Explain how each commit since the latest release affects the next version"`
+	History       historyCommand       `cmd:"history" help:"List the version tags, latest first, with their commit, date, tagger and bump"`
+	Between       betweenCommand       `cmd:"between" help:"Show the bump the commits between two version tags warrant, commit by commit"`
+	Backfill      backfillCommand      `cmd:"backfill" help:"Plan, or create, the version tags missing from the history"`
+	Check         checkCommand         `cmd:"check" help:"Check the next version can be tagged, and the version tags have no gaps or duplicates"`
+}
+
+// repoFlags are the flags shared by every command reading tags and commits.
+type repoFlags struct {
+	Prefix        string   `help:"Version prefix" short:"p"`
+	Pattern       string   `help:"Only consider tags matching this glob pattern"`
+	Path          []string `help:"Only consider commits touching these paths"`
+	IncludeScope  []string `help:"Only consider commits with a scope matching these globs" name:"include-scope"`
+	ExcludeScope  []string `help:"Ignore commits whose scopes all match these globs" name:"exclude-scope"`
+	MergeStrategy string   `help:"How merges are considered: all commits, first-parent commits only, or first-parent with merge titles taken from their body" enum:"all,first-parent,merge-body" default:"all" name:"merge-strategy" env:"SVU_MERGE_STRATEGY"`
+	Unshallow     bool     `help:"Fetch more history and tags when the repository is a shallow clone"`
+	RequireTag    bool     `help:"Fail instead of starting from 0.0.0 when there are no version tags"`
+}
+
+func (f repoFlags) options() semtag.Options {
+	return semtag.Options{
+		Prefix:        f.Prefix,
+		Pattern:       f.Pattern,
+		Paths:         f.Path,
+		IncludeScopes: f.IncludeScope,
+		ExcludeScopes: f.ExcludeScope,
+		MergeStrategy: semtag.MergeStrategy(f.MergeStrategy),
+		Unshallow:     f.Unshallow,
+		RequireTag:    f.RequireTag,
+	}
+}
+
+// rulesFlags are the flags deciding which commit types bump the version.
+type rulesFlags struct {
+	MinorType []string `help:"Commit types bumping the minor version (default: feat)" name:"minor-type"`
+	PatchType []string `help:"Commit types bumping the patch version (default: fix)" name:"patch-type"`
+}
+
+func (f rulesFlags) rules() semtag.Rules {
+	return semtag.Rules{
+		MinorTypes: f.MinorType,
+		PatchTypes: f.PatchType,
+	}
+}
+
+// bumpFlags are the flags deciding how commits bump the version.
+type bumpFlags struct {
+	rulesFlags
+	Bump      string `help:"Force the part of the version to bump, regardless of commits" enum:",major,minor,patch" default:""`
+	ReleaseAs string `help:"Force the next version, which must be greater than the current one" name:"release-as"`
+	// The policy flags refuse versions instead of computing them.
+	MaxBump                string `help:"Highest bump allowed, failing on higher ones; a major bump needs --allow-major or an Approved-Breaking-Change footer" enum:",major,minor,patch" default:"" name:"max-bump" env:"SVU_MAX_BUMP"`
+	AllowMajor             bool   `help:"Approve a major bump beyond --max-bump" name:"allow-major"`
+	MinBreakingDescription int    `help:"Minimum length of the BREAKING CHANGE footer describing each breaking change" name:"min-breaking-description" env:"SVU_MIN_BREAKING_DESCRIPTION"`
+	VerifyAPI              string `help:"Compare the exported Go API with the previous stable tag, and warn or fail when the commits warrant a lower bump than its changes" enum:",warn,fail" default:"" name:"verify-api" env:"SVU_VERIFY_API"`
+	// MaintenanceBranch values are in pattern[=line] format.
+	MaintenanceBranch []string `help:"Maintenance branch glob, optionally with its version line in pattern=line format (e.g. 'release/*' or 'legacy=1.8.x'), refusing versions outside of the line" name:"maintenance-branch" env:"SVU_MAINTENANCE_BRANCHES"`
+}
+
+// apply sets the options controlled by the flags.
+func (f bumpFlags) apply(opts *semtag.Options) error {
+	bump, err := semtag.ParseBump(f.Bump)
+	if err != nil {
+		return err
+	}
+	maxBump, err := semtag.ParseBump(f.MaxBump)
+	if err != nil {
+		return err
+	}
+	maintenance, err := parseMaintenanceBranches(f.MaintenanceBranch)
+	if err != nil {
+		return err
+	}
+	opts.Rules = f.rules()
+	opts.Bump = bump
+	opts.ReleaseAs = f.ReleaseAs
+	opts.Policy = semtag.Policy{
+		MaxBump:                maxBump,
+		AllowMajor:             f.AllowMajor,
+		MinBreakingDescription: f.MinBreakingDescription,
+	}
+	opts.VerifyAPI = semtag.APIVerification(f.VerifyAPI)
+	opts.MaintenanceBranches = maintenance
+	return nil
+}
+
+// validationFlags are the flags of the commands validating the next version.
+type validationFlags struct {
+	SkipValidation bool `help:"Use the next version even when it is not greater than every tag of its stream, or already tagged" name:"skip-validation"`
 }
 
 type nextCommand struct {
-	Prefix       string   `help:"Version prefix" short:"p"`
-	BranchSuffix []string `help:"Custom branch to pre-release suffix mapping in branch:suffix format" name:"branch-suffix"`
+	repoFlags
+	bumpFlags
+	validationFlags
+	Ref           string   `help:"Revision to compute the version of, instead of HEAD"`
+	Branch        string   `help:"Branch to compute the pre-release suffix for, instead of detecting it"`
+	BranchSuffix  []string `help:"Custom branch to pre-release suffix mapping in branch:suffix format" name:"branch-suffix"`
+	PR            bool     `help:"Compute a pull request preview version (e.g. 1.5.0-pr.482.3) instead of a branch pre-release" name:"pr"`
+	PRNumber      int      `help:"Pull request number, instead of detecting it from the CI environment" name:"pr-number"`
+	PRBase        string   `help:"Branch the pull request targets, instead of detecting it from the CI environment" name:"pr-base"`
+	FailUnchanged bool     `help:"Fail with exit status 10 instead of printing the current version when no release is needed" name:"fail-if-unchanged" xor:"unchanged"`
+	EmptyOutput   bool     `help:"Print nothing instead of the current version when no release is needed" name:"empty-output" xor:"unchanged"`
 }
 
 type currentCommand struct {
-	Prefix string `help:"Version prefix" short:"p"`
+	repoFlags
+	Ref string `help:"Revision to get the version of, instead of HEAD"`
+}
+
+type shouldReleaseCommand struct {
+	repoFlags
+	bumpFlags
+	validationFlags
+	Ref string `help:"Revision to compute the version of, instead of HEAD"`
+}
+
+// optionFlags maps the fields of semtag.Options that errors ask to set to
+// their flags.
+var optionFlags = map[string]string{
+	"Unshallow":          "--unshallow",
+	"PullRequest.Number": "--pr-number",
+	"PullRequest.Base":   "--pr-base",
+}
+
+// errorMessage formats err for the command line, naming the flag to pass
+// instead of the library option.
+func errorMessage(err error) string {
+	var optionErr *semtag.OptionError
+	if !errors.As(err, &optionErr) {
+		return err.Error()
+	}
+	flag, ok := optionFlags[optionErr.Field]
+	if !ok {
+		return err.Error()
+	}
+	return strings.Replace(err.Error(), optionErr.Error(), optionErr.Suggest("pass "+flag), 1)
 }
 
 func main() {
@@ -30,52 +171,698 @@ func main() {
 	app := kong.Parse(&root,
 		kong.Name("semtag"),
 		kong.Description("Semantic version tagging helper"),
+		kong.BindTo(context.Background(), (*context.Context)(nil)),
 	)
 
 	if err := app.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		var reported reportedError
+		if !errors.As(err, &reported) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", errorMessage(err))
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
-func (cmd *nextCommand) Run() error {
-	if err := ensureRepo(); err != nil {
+func (cmd *nextCommand) Run(ctx context.Context) error {
+	overrides, err := parseBranchSuffixPairs(cmd.BranchSuffix)
+	if err != nil {
 		return err
 	}
 
-	overrides, err := parseBranchSuffixPairs(cmd.BranchSuffix)
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Branch = cmd.Branch
+	opts.BranchSuffixes = overrides
+	opts.SkipValidation = cmd.SkipValidation
+	opts.PullRequest = semtag.PullRequestOptions{
+		Enabled: cmd.PR,
+		Number:  cmd.PRNumber,
+		Base:    cmd.PRBase,
+	}
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+
+	result, err := semtag.Next(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	version, err := NextVersion(cmd.Prefix, overrides)
+	if result.Override != semtag.OverrideNone {
+		log.Print(result.Reason())
+	} else if result.Commit != nil {
+		log.Printf("detected %s: %s %s", describeBump(result.Bump), result.Commit.SHA, result.Commit.Title)
+	}
+	warnAPIBump(result)
+	warnValidation(result)
+
+	if !result.ReleaseNeeded() {
+		switch {
+		case cmd.FailUnchanged:
+			return fmt.Errorf("%w: %s", semtag.ErrNoReleaseNeeded, result.Reason())
+		case cmd.EmptyOutput:
+			log.Print(result.Reason())
+			return nil
+		}
+	}
+
+	fmt.Println(result.Tag)
+	return nil
+}
+
+func (cmd *currentCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	result, err := semtag.Current(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(formatVersion(cmd.Prefix, version))
+	fmt.Println(result.Tag)
+	return nil
+}
+
+func (cmd *shouldReleaseCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.SkipValidation = cmd.SkipValidation
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+
+	result, err := semtag.Next(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(result.Reason())
+	warnAPIBump(result)
+	warnValidation(result)
+	if !result.ReleaseNeeded() {
+		return reportedError{semtag.ErrNoReleaseNeeded}
+	}
+	return nil
+}
+
+// warnAPIBump warns when the exported Go API changed more than the commits
+// tell, with --verify-api=warn, and when type errors made the comparison
+// incomplete.
+func warnAPIBump(result *semtag.Result) {
+	if result.API != nil && len(result.API.TypeErrors) > 0 {
+		log.Print("warning: the API comparison is incomplete, changes of the symbols affected by these type errors are missed:")
+		for _, err := range result.API.TypeErrors {
+			log.Printf("  %s", err)
+		}
+	}
+	if !result.APIBumpTooLow() {
+		return
+	}
+	log.Printf("warning: the API changes require a %s bump, but the commits warrant %s:", result.API.Bump, result.Bump)
+	for _, change := range result.API.Changes {
+		if change.Bump > result.Bump {
+			log.Printf("  %s", change)
+		}
+	}
+}
+
+// warnValidation warns about the gaps and duplicates found among the version
+// tags while validating the next version.
+func warnValidation(result *semtag.Result) {
+	if result.Validation == nil {
+		return
+	}
+	for _, problem := range result.Validation.Problems {
+		if problem.Kind.Warning() {
+			log.Printf("warning: %s", problem.Message)
+		}
+	}
+}
+
+// reportedError wraps an error the command already explained to the user, so
+// it only decides the exit code.
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+// linkFlags are the flags deciding how issues and pull requests referenced by
+// commits are linked.
+type linkFlags struct {
+	IssueURL       string   `help:"Link template for issues, {id} being replaced with the number (default: derived from the origin remote)" name:"issue-url"`
+	PullRequestURL string   `help:"Link template for pull requests, {id} being replaced with the number (default: derived from the origin remote)" name:"pull-request-url"`
+	Tracker        []string `help:"External issue tracker in pattern=url format, e.g. '[A-Z]+-\\d+=https://example.atlassian.net/browse/{id}'" sep:"none"`
+}
+
+// apply sets the options controlled by the flags.
+func (f linkFlags) apply(opts *semtag.Options) error {
+	opts.Links = semtag.LinkOptions{
+		IssueURL:       f.IssueURL,
+		PullRequestURL: f.PullRequestURL,
+	}
+	for _, entry := range f.Tracker {
+		pattern, url, ok := strings.Cut(entry, "=")
+		if !ok || pattern == "" || url == "" {
+			return fmt.Errorf("invalid tracker format: %s", entry)
+		}
+		opts.Links.Trackers = append(opts.Links.Trackers, semtag.Tracker{Pattern: pattern, URL: url})
+	}
 	return nil
 }
 
-func (cmd *currentCommand) Run() error {
-	if err := ensureRepo(); err != nil {
+// templateFlags are the flags choosing how release notes are rendered.
+type templateFlags struct {
+	Template string `help:"Go text/template file to render the release notes with" type:"existingfile" xor:"template"`
+	Builtin  string `help:"Builtin template to render the release notes with (markdown, slack, html), markdown by default" xor:"template"`
+}
+
+func (f templateFlags) template() (*template.Template, error) {
+	if f.Template == "" {
+		name := f.Builtin
+		if name == "" {
+			name = "markdown"
+		}
+		return semtag.BuiltinTemplate(name)
+	}
+	text, err := os.ReadFile(f.Template)
+	if err != nil {
+		return nil, err
+	}
+	return semtag.ParseTemplate(filepath.Base(f.Template), string(text))
+}
+
+type notesCommand struct {
+	repoFlags
+	bumpFlags
+	validationFlags
+	templateFlags
+	linkFlags
+	Ref         string `help:"Revision to compute the version of, instead of HEAD"`
+	Branch      string `help:"Branch to compute the pre-release suffix for, instead of detecting it"`
+	JSON        bool   `help:"Print the release notes data as JSON instead of rendering a template" name:"json" xor:"template"`
+	ExcludeBots bool   `help:"Leave bots such as dependabot[bot] out of the contributors" name:"exclude-bots"`
+}
+
+func (cmd *notesCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Branch = cmd.Branch
+	opts.SkipValidation = cmd.SkipValidation
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+	if err := cmd.linkFlags.apply(&opts); err != nil {
+		return err
+	}
+	opts.ExcludeBots = cmd.ExcludeBots
+
+	tmpl, err := cmd.template()
+	if err != nil {
+		return err
+	}
+
+	notes, err := semtag.Notes(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if cmd.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(notes)
+	}
+	return notes.Render(os.Stdout, tmpl)
+}
+
+type changelogCommand struct {
+	repoFlags
+	bumpFlags
+	validationFlags
+	linkFlags
+	Ref           string   `help:"Revision to compute the version of, instead of HEAD"`
+	Branch        string   `help:"Branch to compute the pre-release suffix for, instead of detecting it"`
+	File          string   `help:"Changelog file to update, created if missing" default:"CHANGELOG.md" type:"path"`
+	Section       []string `help:"Custom commit type to changelog section mapping in type:section format, replacing the defaults"`
+	RepositoryURL string   `help:"Web URL of the repository for compare links, instead of deriving it from the origin remote" name:"repository-url"`
+	DryRun        bool     `help:"Print the updated changelog instead of writing it" name:"dry-run"`
+}
+
+func (cmd *changelogCommand) Run(ctx context.Context) error {
+	sections, err := parseSectionPairs(cmd.Section)
+	if err != nil {
+		return err
+	}
+
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Branch = cmd.Branch
+	opts.SkipValidation = cmd.SkipValidation
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+	if err := cmd.linkFlags.apply(&opts); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(cmd.File)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	updated, err := semtag.UpdateChangelog(ctx, opts, semtag.ChangelogOptions{
+		Sections:      sections,
+		RepositoryURL: cmd.RepositoryURL,
+	}, string(content))
+	if err != nil {
+		return err
+	}
+
+	if cmd.DryRun {
+		fmt.Print(updated)
+		return nil
+	}
+	if updated == string(content) {
+		log.Printf("%s is up to date", cmd.File)
+		return nil
+	}
+	return os.WriteFile(cmd.File, []byte(updated), 0o644)
+}
+
+type releaseCommand struct {
+	Publish releasePublishCommand `cmd:"publish" help:"Create a release for a tag on GitHub, GitLab or Gitea, with its release notes"`
+}
+
+type releasePublishCommand struct {
+	repoFlags
+	templateFlags
+	linkFlags
+	Tag         string   `help:"Tag to publish, the latest tag reachable from HEAD by default"`
+	Forge       string   `help:"Forge hosting the repository, detected from the origin remote by default" enum:",github,gitlab,gitea" default:""`
+	APIURL      string   `help:"URL of the forge API, e.g. https://gitea.example.com/api/v1" name:"api-url"`
+	Repository  string   `help:"Repository on the forge (owner/repo, or the project path on GitLab), detected from the origin remote by default"`
+	Token       string   `help:"Token to authenticate to the forge API" env:"SEMTAG_TOKEN"`
+	Draft       bool     `help:"Create a draft release"`
+	Asset       []string `help:"Files to attach to the release" type:"existingfile"`
+	ExcludeBots bool     `help:"Leave bots such as dependabot[bot] out of the contributors" name:"exclude-bots"`
+}
+
+func (cmd *releasePublishCommand) Run(ctx context.Context) error {
+	tmpl, err := cmd.template()
+	if err != nil {
+		return err
+	}
+
+	opts := cmd.options()
+	if err := cmd.linkFlags.apply(&opts); err != nil {
+		return err
+	}
+	opts.ExcludeBots = cmd.ExcludeBots
+	tag := cmd.Tag
+	if tag == "" {
+		opts.RequireTag = true
+		current, err := semtag.Current(ctx, opts)
+		if err != nil {
+			return err
+		}
+		tag = current.Tag
+	}
+
+	notes, err := semtag.TagNotes(ctx, opts, tag)
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	if err := notes.Render(&body, tmpl); err != nil {
 		return err
 	}
 
-	version, err := CurrentVersion(cmd.Prefix)
+	publisher, err := cmd.publisher(ctx)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(formatVersion(cmd.Prefix, version))
+	release := forge.Release{
+		Tag:        tag,
+		Name:       tag,
+		Body:       body.String(),
+		Draft:      cmd.Draft,
+		Prerelease: !semtag.IsStableTag(tag),
+	}
+	for _, path := range cmd.Asset {
+		release.Assets = append(release.Assets, forge.Asset{Path: path})
+	}
+
+	url, err := publisher.Publish(ctx, release)
+	if err != nil {
+		return err
+	}
+	fmt.Println(url)
 	return nil
 }
 
-func ensureRepo() error {
-	if git.IsRepo() {
+// forgeTokenVariables are the environment variables the token is read from
+// when neither --token nor SEMTAG_TOKEN is set. Each is only read for its own
+// forge, so that a token is never sent to another forge's API.
+var forgeTokenVariables = map[string]string{
+	forge.GitHub: "GITHUB_TOKEN",
+	forge.GitLab: "GITLAB_TOKEN",
+	forge.Gitea:  "GITEA_TOKEN",
+}
+
+// publisher returns the publisher of the forge set by the flags, completed
+// with what can be detected from the origin remote.
+func (cmd *releasePublishCommand) publisher(ctx context.Context) (forge.ReleasePublisher, error) {
+	kind, cfg, err := cmd.forgeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return forge.New(kind, cfg)
+}
+
+// forgeConfig returns the kind and configuration of the forge set by the
+// flags, completed with what can be detected from the origin remote and the
+// environment.
+func (cmd *releasePublishCommand) forgeConfig(ctx context.Context) (string, forge.Config, error) {
+	kind := cmd.Forge
+	cfg := forge.Config{BaseURL: cmd.APIURL, Repository: cmd.Repository, Token: cmd.Token}
+
+	if kind == "" || cfg.Repository == "" || cfg.BaseURL == "" {
+		repositoryURL, err := semtag.RepositoryURL(ctx)
+		if err != nil && kind == "" {
+			return "", forge.Config{}, fmt.Errorf("%w: pass --forge", forge.ErrUnknownForge)
+		}
+		detectedKind, detected, err := forge.Detect(repositoryURL)
+		switch {
+		case err != nil && kind == "":
+			return "", forge.Config{}, fmt.Errorf("%w, pass --forge", err)
+		case err == nil && (kind == "" || kind == detectedKind):
+			kind = detectedKind
+			if cfg.BaseURL == "" {
+				cfg.BaseURL = detected.BaseURL
+			}
+			if cfg.Repository == "" {
+				cfg.Repository = detected.Repository
+			}
+		}
+	}
+
+	if cfg.Repository == "" {
+		return "", forge.Config{}, errors.New("can't detect the repository on the forge, pass --repository")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(forgeTokenVariables[kind])
+	}
+	return kind, cfg, nil
+}
+
+type explainCommand struct {
+	repoFlags
+	bumpFlags
+	Ref    string `help:"Revision to compute the version of, instead of HEAD"`
+	Branch string `help:"Branch to compute the pre-release suffix for, instead of detecting it"`
+}
+
+func (cmd *explainCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Branch = cmd.Branch
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+
+	explanation, err := semtag.Explain(ctx, opts)
+	if err != nil {
+		return err
+	}
+	result := explanation.Result
+	warnAPIBump(result)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	previous := result.PreviousTag
+	if previous == "" {
+		previous = "none"
+	}
+	fmt.Fprintf(w, "previous:\t%s\n", previous)
+	fmt.Fprintf(w, "next:\t%s\n", result.Tag)
+	fmt.Fprintf(w, "reason:\t%s\n", result.Reason())
+	if filters := describeFilters(opts); filters != "" {
+		fmt.Fprintf(w, "filters:\t%s\n", filters)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return printExplainedCommits(explanation.Commits)
+}
+
+// printExplainedCommits prints what each commit means for the version, after
+// an empty line, if there are any.
+func printExplainedCommits(commits []semtag.ExplainedCommit) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, c := range commits {
+		effect := c.Bump.String()
+		switch {
+		case c.Excluded != "":
+			effect = "excluded"
+		case c.Bump == semtag.BumpNone:
+			effect = "-"
+		}
+		var notes []string
+		if c.Excluded != "" {
+			notes = append(notes, c.Excluded)
+		}
+		if c.ReleaseAs != "" {
+			notes = append(notes, "Release-As "+c.ReleaseAs)
+		}
+		if c.Decisive {
+			notes = append(notes, "decided the version")
+		}
+		fmt.Fprintf(w, "%.7s\t%s\t%s", c.Commit.SHA, effect, c.Commit.Title)
+		if len(notes) > 0 {
+			fmt.Fprintf(w, "\t(%s)", strings.Join(notes, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+type betweenCommand struct {
+	repoFlags
+	rulesFlags
+	From string `arg:"" help:"Version tag to compare from"`
+	To   string `arg:"" help:"Version tag to compare to"`
+}
+
+func (cmd *betweenCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Rules = cmd.rules()
+
+	comparison, err := semtag.Between(ctx, opts, cmd.From, cmd.To)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "from:\t%s\n", comparison.From)
+	fmt.Fprintf(w, "to:\t%s\n", comparison.To)
+	bump := comparison.Bump.String()
+	switch {
+	case comparison.Override != semtag.OverrideNone:
+		bump += fmt.Sprintf(" (forced by %s: %.7s %s)", comparison.Override, comparison.Commit.SHA, comparison.Commit.Title)
+	case comparison.Commit != nil:
+		bump += fmt.Sprintf(" (%.7s %s)", comparison.Commit.SHA, comparison.Commit.Title)
+	}
+	fmt.Fprintf(w, "bump:\t%s\n", bump)
+	fmt.Fprintf(w, "version:\t%s\n", comparison.Version.String())
+	fmt.Fprintf(w, "tagged:\t%s\n", comparison.Tagged)
+	if filters := describeFilters(opts); filters != "" {
+		fmt.Fprintf(w, "filters:\t%s\n", filters)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return printExplainedCommits(comparison.Commits)
+}
+
+type backfillCommand struct {
+	repoFlags
+	rulesFlags
+	Ref           string `help:"Revision to backfill the first-parent history of, instead of HEAD"`
+	From          string `help:"Revision to start after, instead of the latest stable tag"`
+	ReleasePoints string `help:"Commits given a version: merge commits, or every commit warranting a bump" enum:"merges,commits" default:"merges" name:"release-points"`
+	Create        bool   `help:"Create the planned tags, instead of only printing them"`
+}
+
+func (cmd *backfillCommand) Run(ctx context.Context) error {
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Rules = cmd.rules()
+
+	plan, err := semtag.Backfill(ctx, opts, semtag.BackfillOptions{
+		From:          cmd.From,
+		ReleasePoints: semtag.ReleasePoints(cmd.ReleasePoints),
+	})
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		log.Print("no tags to backfill")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, planned := range plan {
+		fmt.Fprintf(w, "%s\t%.7s\t%s\t%s\n", planned.Tag, planned.Commit.SHA, planned.Bump, planned.Commit.Title)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !cmd.Create {
+		log.Printf("%d tags planned, pass --create to create them", len(plan))
 		return nil
 	}
-	return errors.New("current directory is not a git repository")
+	if err := semtag.CreateTags(ctx, plan); err != nil {
+		return err
+	}
+	log.Printf("%d tags created, push them with: git push origin --tags", len(plan))
+	return nil
+}
+
+type historyCommand struct {
+	Prefix  string `help:"Version prefix" short:"p"`
+	Pattern string `help:"Only consider tags matching this glob pattern"`
+	JSON    bool   `help:"Print the releases as JSON instead of a table" name:"json"`
+}
+
+func (cmd *historyCommand) Run(ctx context.Context) error {
+	releases, err := semtag.History(ctx, semtag.Options{Prefix: cmd.Prefix, Pattern: cmd.Pattern})
+	if err != nil {
+		return err
+	}
+	if cmd.JSON {
+		if releases == nil {
+			releases = []semtag.Release{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(releases)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tCOMMIT\tDATE\tTAGGER\tPRE-RELEASE\tBUMP")
+	for _, r := range releases {
+		prerelease := "no"
+		if r.Prerelease {
+			prerelease = "yes"
+		}
+		bump := r.Bump.String()
+		if r.Bump == semtag.BumpNone {
+			bump = "-"
+		}
+		fmt.Fprintf(w, "%s\t%.7s\t%s\t%s\t%s\t%s\n", r.Tag, r.Commit, r.Date.Format(time.DateOnly), r.Tagger.Name, prerelease, bump)
+	}
+	return w.Flush()
+}
+
+type checkCommand struct {
+	repoFlags
+	bumpFlags
+	Ref          string   `help:"Revision to compute the version of, instead of HEAD"`
+	Branch       string   `help:"Branch to compute the pre-release suffix for, instead of detecting it"`
+	BranchSuffix []string `help:"Custom branch to pre-release suffix mapping in branch:suffix format" name:"branch-suffix"`
+	Strict       bool     `help:"Fail on warnings too, such as gaps and duplicate tags"`
+	Version      string   `arg:"" optional:"" help:"Version or tag to check, instead of the next version"`
+}
+
+func (cmd *checkCommand) Run(ctx context.Context) error {
+	overrides, err := parseBranchSuffixPairs(cmd.BranchSuffix)
+	if err != nil {
+		return err
+	}
+
+	opts := cmd.options()
+	opts.Ref = cmd.Ref
+	opts.Branch = cmd.Branch
+	opts.BranchSuffixes = overrides
+	opts.SkipValidation = true
+	if err := cmd.bumpFlags.apply(&opts); err != nil {
+		return err
+	}
+
+	var version *semver.Version
+	if cmd.Version != "" {
+		version, err = semver.NewVersion(strings.TrimPrefix(cmd.Version, cmd.Prefix))
+		if err != nil {
+			return fmt.Errorf("invalid version '%s': %w", cmd.Version, err)
+		}
+	} else {
+		result, err := semtag.Next(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if result.ReleaseNeeded() {
+			version = &result.Version
+		}
+	}
+
+	validation, err := semtag.Validate(ctx, opts, version)
+	if err != nil {
+		return err
+	}
+	for _, problem := range validation.Problems {
+		severity := "error"
+		if problem.Kind.Warning() {
+			severity = "warning"
+		}
+		fmt.Printf("%s: %s\n", severity, problem.Message)
+	}
+
+	err = validation.Err()
+	if cmd.Strict && len(validation.Problems) > 0 {
+		err = &semtag.ValidationError{Tag: validation.Tag, Problems: validation.Problems}
+	}
+	if err != nil {
+		return reportedError{err}
+	}
+	if validation.Tag != "" {
+		fmt.Printf("%s can be tagged\n", validation.Tag)
+	}
+	return nil
+}
+
+// describeFilters summarizes the filters restricting the commits considered.
+func describeFilters(opts semtag.Options) string {
+	var filters []string
+	if len(opts.Paths) > 0 {
+		filters = append(filters, "paths "+strings.Join(opts.Paths, ", "))
+	}
+	if len(opts.IncludeScopes) > 0 {
+		filters = append(filters, "scopes "+strings.Join(opts.IncludeScopes, ", "))
+	}
+	if len(opts.ExcludeScopes) > 0 {
+		filters = append(filters, "not scopes "+strings.Join(opts.ExcludeScopes, ", "))
+	}
+	return strings.Join(filters, "; ")
+}
+
+func describeBump(bump semtag.Bump) string {
+	switch bump {
+	case semtag.BumpMajor:
+		return "breaking change"
+	case semtag.BumpMinor:
+		return "feature"
+	case semtag.BumpPatch:
+		return "fix"
+	}
+	return bump.String()
 }
 
 func parseBranchSuffixPairs(values []string) (map[string]string, error) {
@@ -111,12 +898,42 @@ func parseBranchSuffixPairs(values []string) (map[string]string, error) {
 	return mapping, nil
 }
 
-func formatVersion(prefix, version string) string {
-	if prefix == "" {
-		return version
+func parseMaintenanceBranches(values []string) ([]semtag.MaintenanceBranch, error) {
+	var branches []semtag.MaintenanceBranch
+	for _, entry := range values {
+		candidate := strings.TrimSpace(entry)
+		if candidate == "" {
+			continue
+		}
+
+		pattern, line, _ := strings.Cut(candidate, "=")
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("invalid maintenance-branch format: %s", entry)
+		}
+
+		branches = append(branches, semtag.MaintenanceBranch{
+			Pattern: pattern,
+			Line:    strings.TrimSpace(line),
+		})
+	}
+	return branches, nil
+}
+
+func parseSectionPairs(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
 	}
-	if strings.HasPrefix(version, prefix) {
-		return version
+
+	mapping := make(map[string]string)
+	for _, entry := range values {
+		commitType, section, ok := strings.Cut(entry, ":")
+		commitType = strings.ToLower(strings.TrimSpace(commitType))
+		section = strings.TrimSpace(section)
+		if !ok || commitType == "" || section == "" {
+			return nil, fmt.Errorf("invalid section format: %s", entry)
+		}
+		mapping[commitType] = section
 	}
-	return prefix + version
+	return mapping, nil
 }
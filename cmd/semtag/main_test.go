@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/google-internal/semtag/pkg/forge"
+	"github.com/google-internal/semtag/pkg/semtag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoReleaseNeeded(t *testing.T) {
+	tempRepo(t)
+	gitRun(t, "commit", "--allow-empty", "-m", "chore: first")
+	gitRun(t, "tag", "v1.0.0")
+	gitRun(t, "commit", "--allow-empty", "-m", "docs: readme")
+
+	for _, args := range [][]string{
+		{"should-release"},
+		{"next", "--fail-if-unchanged"},
+	} {
+		err := runCLI(t, args...)
+		require.ErrorIs(t, err, semtag.ErrNoReleaseNeeded)
+		require.Equal(t, exitNoReleaseNeeded, exitCode(err))
+	}
+	require.NoError(t, runCLI(t, "next"))
+	require.NoError(t, runCLI(t, "next", "--empty-output"))
+
+	gitRun(t, "commit", "--allow-empty", "-m", "fix: bug")
+	require.NoError(t, runCLI(t, "should-release"))
+	require.NoError(t, runCLI(t, "next", "--fail-if-unchanged"))
+}
+
+func TestErrorMessage(t *testing.T) {
+	tempRepo(t)
+	gitRun(t, "commit", "--allow-empty", "-m", "chore: first")
+	err := runCLI(t, "next", "--pr", "--pr-base", "main")
+	var optionErr *semtag.OptionError
+	require.ErrorAs(t, err, &optionErr)
+	require.Equal(t, "PullRequest.Number", optionErr.Field)
+	require.EqualError(t, err, "could not detect the pull request number: set Options.PullRequest.Number")
+	require.Equal(t, "could not detect the pull request number: pass --pr-number", errorMessage(err))
+
+	err = fmt.Errorf("wrapped: %w", &semtag.OptionError{
+		Field:       "Unshallow",
+		Alternative: "fetch the full history",
+		Err:         semtag.ErrShallowRepository,
+	})
+	require.Equal(t, exitShallowRepo, exitCode(err))
+	require.Equal(t, "wrapped: "+semtag.ErrShallowRepository.Error()+": fetch the full history or pass --unshallow", errorMessage(err))
+}
+
+func TestValidationWarnings(t *testing.T) {
+	tempRepo(t)
+	gitRun(t, "commit", "--allow-empty", "-m", "feat: first")
+	gitRun(t, "tag", "v1.0.0")
+	gitRun(t, "tag", "v1.0.0+build.1")
+	gitRun(t, "commit", "--allow-empty", "-m", "fix: bug")
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	for _, command := range []string{"next", "should-release"} {
+		out.Reset()
+		require.NoError(t, runCLI(t, command))
+		require.Contains(t, out.String(), "warning: tags v1.0.0 and v1.0.0+build.1 are the same version")
+	}
+}
+
+// runCLI runs the command line args like main does, returning the error
+// instead of exiting.
+func runCLI(tb testing.TB, args ...string) error {
+	tb.Helper()
+	var root cli
+	parser, err := kong.New(&root,
+		kong.Name("semtag"),
+		kong.BindTo(context.Background(), (*context.Context)(nil)),
+	)
+	require.NoError(tb, err)
+	app, err := parser.Parse(args)
+	require.NoError(tb, err)
+	return app.Run()
+}
+
+func tempRepo(tb testing.TB) {
+	tb.Helper()
+	// The branch and pull request must not be detected from the CI running
+	// the tests.
+	for _, name := range git.CIVariables() {
+		tb.Setenv(name, "")
+	}
+	previous, err := os.Getwd()
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		require.NoError(tb, os.Chdir(previous))
+	})
+	require.NoError(tb, os.Chdir(tb.TempDir()))
+	gitRun(tb, "init", "--initial-branch", "main")
+}
+
+func gitRun(tb testing.TB, args ...string) {
+	tb.Helper()
+	allArgs := []string{
+		"-c", "user.name=svu",
+		"-c", "user.email=svu@example.com",
+		"-c", "commit.gpgSign=false",
+		"-c", "tag.gpgSign=false",
+	}
+	out, err := exec.Command("git", append(allArgs, args...)...).CombinedOutput()
+	require.NoError(tb, err, string(out))
+}
+
+func TestForgeToken(t *testing.T) {
+	tempRepo(t)
+	gitRun(t, "remote", "add", "origin", "https://gitlab.example.com/group/project.git")
+	t.Setenv("SEMTAG_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	t.Setenv("GITLAB_TOKEN", "")
+
+	cmd := &releasePublishCommand{}
+	kind, cfg, err := cmd.forgeConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, forge.GitLab, kind)
+	require.Empty(t, cfg.Token)
+
+	t.Setenv("GITLAB_TOKEN", "gitlab-token")
+	_, cfg, err = cmd.forgeConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "gitlab-token", cfg.Token)
+
+	cmd.Token = "flag-token"
+	_, cfg, err = cmd.forgeConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "flag-token", cfg.Token)
+}
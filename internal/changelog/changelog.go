@@ -0,0 +1,307 @@
+// Package changelog reads and updates changelogs following the Keep a
+// Changelog format (https://keepachangelog.com/en/1.1.0/).
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Unreleased is the title of the release collecting upcoming changes.
+const Unreleased = "Unreleased"
+
+// Sections are the standard change types, in the order they are rendered.
+// Other sections are kept, after these.
+var Sections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+var (
+	releaseHeading = regexp.MustCompile(`^##\s+\[?([^\]\s]+)\]?(?:\s+-\s+(\S+))?`)
+	sectionHeading = regexp.MustCompile(`^###\s+(.+?)\s*$`)
+	linkDefinition = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)\s*$`)
+	compareURL     = regexp.MustCompile(`^(.*)/compare/(.+)\.\.\.(.+)$`)
+)
+
+// Changelog is a parsed changelog. Releases which are not modified are
+// rendered as they were read.
+type Changelog struct {
+	// Header is everything before the first release.
+	Header   string
+	Releases []*Release
+	Links    []Link
+}
+
+// Release is a "## [version] - date" block of the changelog.
+type Release struct {
+	// Title is the version, or Unreleased.
+	Title string
+	Date  string
+	// Preamble is the text between the heading and the first section.
+	Preamble string
+	Sections []*Section
+
+	raw      string
+	modified bool
+}
+
+// Section is a "### Added" block of a release, holding its entries as
+// markdown lines.
+type Section struct {
+	Name  string
+	Lines []string
+}
+
+// Link is a link reference definition, as used by the compare links at the
+// bottom of the changelog.
+type Link struct {
+	Name string
+	URL  string
+}
+
+// Parse parses a changelog.
+func Parse(text string) *Changelog {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	c := &Changelog{}
+	var header []string
+	var current *Release
+	var raw []string
+	var section *Section
+
+	flush := func() {
+		if current != nil {
+			current.raw = strings.TrimRight(strings.Join(raw, "\n"), "\n")
+			current.Preamble = strings.TrimSpace(current.Preamble)
+			for _, s := range current.Sections {
+				s.Lines = trimBlankLines(s.Lines)
+			}
+			c.Releases = append(c.Releases, current)
+		}
+	}
+
+	for _, line := range lines {
+		if m := linkDefinition.FindStringSubmatch(line); m != nil {
+			c.Links = append(c.Links, Link{Name: m[1], URL: m[2]})
+			continue
+		}
+
+		if m := releaseHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &Release{Title: m[1], Date: m[2]}
+			raw = []string{line}
+			section = nil
+			continue
+		}
+
+		if current == nil {
+			header = append(header, line)
+			continue
+		}
+
+		raw = append(raw, line)
+		if m := sectionHeading.FindStringSubmatch(line); m != nil {
+			section = &Section{Name: m[1]}
+			current.Sections = append(current.Sections, section)
+			continue
+		}
+		if section != nil {
+			section.Lines = append(section.Lines, line)
+		} else {
+			current.Preamble += line + "\n"
+		}
+	}
+	flush()
+
+	c.Header = strings.TrimSpace(strings.Join(header, "\n"))
+	return c
+}
+
+// Find returns the release with the given title, or nil.
+func (c *Changelog) Find(title string) *Release {
+	for _, r := range c.Releases {
+		if strings.EqualFold(r.Title, title) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Link returns the URL of the link with the given name, or an empty string.
+func (c *Changelog) Link(name string) string {
+	for _, l := range c.Links {
+		if strings.EqualFold(l.Name, name) {
+			return l.URL
+		}
+	}
+	return ""
+}
+
+func (c *Changelog) setLink(name, url string) {
+	for i := range c.Links {
+		if strings.EqualFold(c.Links[i].Name, name) {
+			c.Links[i].URL = url
+			return
+		}
+	}
+	c.Links = append(c.Links, Link{Name: name, URL: url})
+}
+
+// Section returns the section with the given name, creating it if needed.
+func (r *Release) Section(name string) *Section {
+	for _, s := range r.Sections {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	s := &Section{Name: name}
+	r.Sections = append(r.Sections, s)
+	r.modified = true
+	return s
+}
+
+// AddEntry adds a "- text" entry to the section of the release, unless it is
+// already there.
+func (r *Release) AddEntry(section, text string) {
+	s := r.Section(section)
+	entry := "- " + text
+	for _, line := range s.Lines {
+		if strings.TrimSpace(line) == entry {
+			return
+		}
+	}
+	s.Lines = append(s.Lines, entry)
+	r.modified = true
+}
+
+// ReleaseOptions describes a release being cut.
+type ReleaseOptions struct {
+	Version string
+	Date    string
+	// Tag and PreviousTag are used for the compare links. PreviousTag is
+	// empty for the first release.
+	Tag         string
+	PreviousTag string
+	// RepositoryURL is used to create compare links when the changelog has
+	// none yet, e.g. https://github.com/owner/repo. Links are left alone
+	// when both are missing.
+	RepositoryURL string
+}
+
+// Cut moves the Unreleased changes under a new release, leaving an empty
+// Unreleased section, and updates the compare links. It does nothing if the
+// version was already released, so running it twice gives the same result.
+// It returns the release of the version, and whether it was created.
+func (c *Changelog) Cut(opts ReleaseOptions) (*Release, bool) {
+	if existing := c.Find(opts.Version); existing != nil {
+		return existing, false
+	}
+
+	idx := slices.IndexFunc(c.Releases, func(r *Release) bool { return strings.EqualFold(r.Title, Unreleased) })
+	if idx < 0 {
+		c.Releases = slices.Insert(c.Releases, 0, &Release{Title: Unreleased, modified: true})
+		idx = 0
+	}
+	unreleased := c.Releases[idx]
+
+	release := &Release{
+		Title:    opts.Version,
+		Date:     opts.Date,
+		Preamble: unreleased.Preamble,
+		Sections: unreleased.Sections,
+		modified: true,
+	}
+	unreleased.Preamble = ""
+	unreleased.Sections = nil
+	unreleased.modified = true
+	c.Releases = slices.Insert(c.Releases, idx+1, release)
+
+	c.updateLinks(opts)
+	return release, true
+}
+
+func (c *Changelog) updateLinks(opts ReleaseOptions) {
+	var base string
+	if m := compareURL.FindStringSubmatch(c.Link(Unreleased)); m != nil {
+		base = m[1]
+	} else if opts.RepositoryURL != "" {
+		base = strings.TrimSuffix(opts.RepositoryURL, "/")
+	} else {
+		return
+	}
+
+	versionURL := base + "/releases/tag/" + opts.Tag
+	if opts.PreviousTag != "" {
+		versionURL = base + "/compare/" + opts.PreviousTag + "..." + opts.Tag
+	}
+
+	c.setLink(Unreleased, base+"/compare/"+opts.Tag+"...HEAD")
+	idx := slices.IndexFunc(c.Links, func(l Link) bool { return strings.EqualFold(l.Name, Unreleased) })
+	c.Links = slices.Insert(c.Links, idx+1, Link{Name: opts.Version, URL: versionURL})
+}
+
+// String renders the changelog.
+func (c *Changelog) String() string {
+	var blocks []string
+	if c.Header != "" {
+		blocks = append(blocks, c.Header)
+	}
+	for _, r := range c.Releases {
+		blocks = append(blocks, r.String())
+	}
+	if len(c.Links) > 0 {
+		var links []string
+		for _, l := range c.Links {
+			links = append(links, fmt.Sprintf("[%s]: %s", l.Name, l.URL))
+		}
+		blocks = append(blocks, strings.Join(links, "\n"))
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// String renders the release. Releases which were not modified since they
+// were parsed are rendered as they were.
+func (r *Release) String() string {
+	if !r.modified && r.raw != "" {
+		return r.raw
+	}
+
+	heading := "## [" + r.Title + "]"
+	if r.Date != "" {
+		heading += " - " + r.Date
+	}
+	blocks := []string{heading}
+	if r.Preamble != "" {
+		blocks = append(blocks, r.Preamble)
+	}
+
+	sections := slices.Clone(r.Sections)
+	slices.SortStableFunc(sections, func(a, b *Section) int {
+		return sectionOrder(a.Name) - sectionOrder(b.Name)
+	})
+	for _, s := range sections {
+		if lines := trimBlankLines(s.Lines); len(lines) > 0 {
+			blocks = append(blocks, "### "+s.Name+"\n\n"+strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func sectionOrder(name string) int {
+	for i, s := range Sections {
+		if strings.EqualFold(s, name) {
+			return i
+		}
+	}
+	return len(Sections)
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
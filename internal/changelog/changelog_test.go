@@ -0,0 +1,139 @@
+package changelog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const example = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+### Fixed
+
+- Crash on startup.
+
+### Added
+- Export to CSV.
+  With a second line.
+
+## [1.1.0] - 2024-01-02
+
+### Added
+
+* Something   oddly formatted.
+
+## [1.0.0] - 2023-12-01
+
+Initial release.
+
+[Unreleased]: https://github.com/foo/bar/compare/v1.1.0...HEAD
+[1.1.0]: https://github.com/foo/bar/compare/v1.0.0...v1.1.0
+[1.0.0]: https://github.com/foo/bar/releases/tag/v1.0.0
+`
+
+func TestParse(t *testing.T) {
+	c := Parse(example)
+	require.Equal(t, "# Changelog\n\nAll notable changes to this project will be documented in this file.", c.Header)
+	require.Len(t, c.Releases, 3)
+
+	unreleased := c.Find(Unreleased)
+	require.NotNil(t, unreleased)
+	require.Len(t, unreleased.Sections, 2)
+	require.Equal(t, []string{"- Export to CSV.", "  With a second line."}, unreleased.Section("Added").Lines)
+
+	release := c.Find("1.0.0")
+	require.Equal(t, "2023-12-01", release.Date)
+	require.Equal(t, "Initial release.", release.Preamble)
+
+	require.Equal(t, "https://github.com/foo/bar/compare/v1.1.0...HEAD", c.Link(Unreleased))
+	require.Len(t, c.Links, 3)
+
+	require.Equal(t, example, c.String())
+}
+
+func TestCut(t *testing.T) {
+	c := Parse(example)
+	release, created := c.Cut(ReleaseOptions{
+		Version:     "1.2.0",
+		Date:        "2024-02-03",
+		Tag:         "v1.2.0",
+		PreviousTag: "v1.1.0",
+	})
+	require.True(t, created)
+	release.AddEntry("Fixed", "Crash on startup.")
+	release.AddEntry("Security", "Escape user input.")
+
+	require.Equal(t, `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [1.2.0] - 2024-02-03
+
+### Added
+
+- Export to CSV.
+  With a second line.
+
+### Fixed
+
+- Crash on startup.
+
+### Security
+
+- Escape user input.
+
+## [1.1.0] - 2024-01-02
+
+### Added
+
+* Something   oddly formatted.
+
+## [1.0.0] - 2023-12-01
+
+Initial release.
+
+[Unreleased]: https://github.com/foo/bar/compare/v1.2.0...HEAD
+[1.2.0]: https://github.com/foo/bar/compare/v1.1.0...v1.2.0
+[1.1.0]: https://github.com/foo/bar/compare/v1.0.0...v1.1.0
+[1.0.0]: https://github.com/foo/bar/releases/tag/v1.0.0
+`, c.String())
+
+	t.Run("idempotent", func(t *testing.T) {
+		again := Parse(c.String())
+		_, created := again.Cut(ReleaseOptions{Version: "1.2.0", Tag: "v1.2.0", PreviousTag: "v1.1.0"})
+		require.False(t, created)
+		require.Equal(t, c.String(), again.String())
+	})
+}
+
+func TestCutWithoutUnreleasedNorLinks(t *testing.T) {
+	c := Parse("# Changelog\n")
+	release, created := c.Cut(ReleaseOptions{
+		Version:       "0.1.0",
+		Date:          "2024-02-03",
+		Tag:           "v0.1.0",
+		RepositoryURL: "https://gitlab.com/foo/bar/-",
+	})
+	require.True(t, created)
+	release.AddEntry("Added", "First feature.")
+
+	require.Equal(t, `# Changelog
+
+## [Unreleased]
+
+## [0.1.0] - 2024-02-03
+
+### Added
+
+- First feature.
+
+[Unreleased]: https://gitlab.com/foo/bar/-/compare/v0.1.0...HEAD
+[0.1.0]: https://gitlab.com/foo/bar/-/releases/tag/v0.1.0
+`, c.String())
+}
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ciBranchVariables are the environment variables CI systems use to expose the
+// branch being built, in order of precedence. Pull request source branches
+// come before the generic ones, as the latter usually hold a merge ref there.
+var ciBranchVariables = []string{
+	"GITHUB_HEAD_REF",                     // GitHub Actions, pull requests
+	"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", // GitLab CI, merge requests
+	"CHANGE_BRANCH",                       // Jenkins, pull requests
+	"SYSTEM_PULLREQUEST_SOURCEBRANCH",     // Azure Pipelines, pull requests
+	"TRAVIS_PULL_REQUEST_BRANCH",          // Travis CI, pull requests
+	"DRONE_SOURCE_BRANCH",                 // Drone, pull requests
+	"GITHUB_REF_NAME",                     // GitHub Actions
+	"CI_COMMIT_BRANCH",                    // GitLab CI
+	"CI_COMMIT_REF_NAME",                  // GitLab CI, also set for tags
+	"BUILDKITE_BRANCH",                    // Buildkite
+	"BRANCH_NAME",                         // Jenkins multibranch pipelines
+	"GIT_BRANCH",                          // Jenkins git plugin
+	"CIRCLE_BRANCH",                       // CircleCI
+	"TRAVIS_BRANCH",                       // Travis CI
+	"BUILD_SOURCEBRANCH",                  // Azure Pipelines
+	"BITBUCKET_BRANCH",                    // Bitbucket Pipelines
+	"DRONE_BRANCH",                        // Drone
+}
+
+// branchFromCI returns the branch being built according to the CI
+// environment, or an empty string when it can't be determined.
+func branchFromCI() string {
+	return branchFromEnv(os.Getenv)
+}
+
+func branchFromEnv(getenv func(string) string) string {
+	for _, name := range ciBranchVariables {
+		value := strings.TrimSpace(getenv(name))
+		if value == "" {
+			continue
+		}
+
+		// Both hold the tag name when a tag is being built.
+		if name == "GITHUB_REF_NAME" && getenv("GITHUB_REF_TYPE") == "tag" {
+			continue
+		}
+		if name == "CI_COMMIT_REF_NAME" && getenv("CI_COMMIT_TAG") != "" {
+			continue
+		}
+
+		if branch := normalizeBranch(value); branch != "" {
+			return branch
+		}
+	}
+	return ""
+}
+
+// normalizeBranch turns a ref as found in CI variables into a branch name. It
+// returns an empty string for refs which are not branches, such as tags and
+// pull request merge refs.
+func normalizeBranch(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/remotes/"):
+		_, branch, _ := strings.Cut(strings.TrimPrefix(ref, "refs/remotes/"), "/")
+		return branch
+	case strings.HasPrefix(ref, "refs/"):
+		return ""
+	case strings.HasPrefix(ref, "origin/"):
+		return strings.TrimPrefix(ref, "origin/")
+	case isPullRequestRefName(ref):
+		return ""
+	}
+	return ref
+}
+
+// isPullRequestRefName reports whether ref looks like the short name of a
+// pull request ref, such as GitHub's "482/merge".
+func isPullRequestRefName(ref string) bool {
+	number, kind, ok := strings.Cut(ref, "/")
+	if !ok || (kind != "merge" && kind != "head") || number == "" {
+		return false
+	}
+	for i := range len(number) {
+		if !isDigit(number[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ciPullRequestVariables are the environment variables CI systems use to
+// expose the number of the pull request being built.
+var ciPullRequestVariables = []string{
+	"GITHUB_REF",                           // GitHub Actions, refs/pull/N/merge
+	"CI_MERGE_REQUEST_IID",                 // GitLab CI
+	"CHANGE_ID",                            // Jenkins
+	"BUILDKITE_PULL_REQUEST",               // Buildkite, "false" outside pull requests
+	"SYSTEM_PULLREQUEST_PULLREQUESTNUMBER", // Azure Pipelines
+	"TRAVIS_PULL_REQUEST",                  // Travis CI, "false" outside pull requests
+	"CIRCLE_PULL_REQUEST",                  // CircleCI, URL of the pull request
+	"DRONE_PULL_REQUEST",                   // Drone
+	"BITBUCKET_PR_ID",                      // Bitbucket Pipelines
+}
+
+// ciPullRequestBaseVariables are the environment variables CI systems use to
+// expose the branch a pull request targets.
+var ciPullRequestBaseVariables = []string{
+	"GITHUB_BASE_REF",                     // GitHub Actions
+	"CI_MERGE_REQUEST_TARGET_BRANCH_NAME", // GitLab CI
+	"CHANGE_TARGET",                       // Jenkins
+	"BUILDKITE_PULL_REQUEST_BASE_BRANCH",  // Buildkite
+	"SYSTEM_PULLREQUEST_TARGETBRANCH",     // Azure Pipelines
+	"DRONE_TARGET_BRANCH",                 // Drone
+	"BITBUCKET_PR_DESTINATION_BRANCH",     // Bitbucket Pipelines
+}
+
+// CIVariables returns the names of all the environment variables the branch
+// and the pull request are detected from, so that tests can clear them.
+func CIVariables() []string {
+	names := []string{"GITHUB_REF_TYPE", "CI_COMMIT_TAG"}
+	names = append(names, ciBranchVariables...)
+	names = append(names, ciPullRequestVariables...)
+	return append(names, ciPullRequestBaseVariables...)
+}
+
+// PullRequest is the pull request being built.
+type PullRequest struct {
+	Number int
+	// Base is the branch the pull request targets, if known.
+	Base string
+	// MergeRef is true when HEAD is the merge of the pull request into its
+	// base, as with GitHub's refs/pull/N/merge, rather than its head.
+	MergeRef bool
+}
+
+// PullRequestFromCI returns the pull request being built according to the CI
+// environment, or nil if the build is not for a pull request.
+func PullRequestFromCI() *PullRequest {
+	return pullRequestFromEnv(os.Getenv)
+}
+
+func pullRequestFromEnv(getenv func(string) string) *PullRequest {
+	for _, name := range ciPullRequestVariables {
+		value := strings.TrimSpace(getenv(name))
+		var number int
+		var mergeRef bool
+		switch name {
+		case "GITHUB_REF":
+			number, mergeRef = pullRequestFromRef(value)
+		case "CIRCLE_PULL_REQUEST":
+			number = parsePullRequestNumber(value[strings.LastIndex(value, "/")+1:])
+		default:
+			number = parsePullRequestNumber(value)
+		}
+		if number == 0 {
+			continue
+		}
+
+		pr := &PullRequest{Number: number, MergeRef: mergeRef}
+		for _, name := range ciPullRequestBaseVariables {
+			if base := normalizeBranch(strings.TrimSpace(getenv(name))); base != "" {
+				pr.Base = base
+				break
+			}
+		}
+		return pr
+	}
+	return nil
+}
+
+// PullRequestFromRefs returns the pull request whose ref (refs/pull/N/merge or
+// refs/pull/N/head, possibly fetched under a remote) points at HEAD, or nil.
+func PullRequestFromRefs(ctx context.Context) *PullRequest {
+	out, err := run(ctx, "for-each-ref", "--points-at", "HEAD", "--format=%(refname)", "refs/pull/", "refs/remotes/")
+	if err != nil {
+		return nil
+	}
+	var found *PullRequest
+	for _, ref := range strings.Split(strings.TrimSpace(out), "\n") {
+		if idx := strings.Index(ref, "/pull/"); idx >= 0 {
+			ref = "refs" + ref[idx:]
+		}
+		number, mergeRef := pullRequestFromRef(ref)
+		if number == 0 {
+			continue
+		}
+		// Prefer merge refs, HEAD points at both when the base hasn't moved.
+		if found == nil || (mergeRef && !found.MergeRef) {
+			found = &PullRequest{Number: number, MergeRef: mergeRef}
+		}
+	}
+	return found
+}
+
+// pullRequestFromRef extracts the number from refs/pull/N/merge and
+// refs/pull/N/head, returning 0 for any other ref.
+func pullRequestFromRef(ref string) (number int, mergeRef bool) {
+	rest, ok := strings.CutPrefix(ref, "refs/pull/")
+	if !ok || !isPullRequestRefName(rest) {
+		return 0, false
+	}
+	id, kind, _ := strings.Cut(rest, "/")
+	return parsePullRequestNumber(id), kind == "merge"
+}
+
+func parsePullRequestNumber(value string) int {
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,239 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchFromEnv(t *testing.T) {
+	for name, tt := range map[string]struct {
+		env      map[string]string
+		expected string
+	}{
+		"none": {
+			env:      map[string]string{},
+			expected: "",
+		},
+		"github push": {
+			env:      map[string]string{"GITHUB_REF_NAME": "main", "GITHUB_REF_TYPE": "branch"},
+			expected: "main",
+		},
+		"github pull request": {
+			env:      map[string]string{"GITHUB_HEAD_REF": "feature/foo", "GITHUB_REF_NAME": "482/merge"},
+			expected: "feature/foo",
+		},
+		"github merge ref only": {
+			env:      map[string]string{"GITHUB_REF_NAME": "482/merge"},
+			expected: "",
+		},
+		"github tag": {
+			env:      map[string]string{"GITHUB_REF_NAME": "v1.2.3", "GITHUB_REF_TYPE": "tag"},
+			expected: "",
+		},
+		"gitlab merge request": {
+			env:      map[string]string{"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "fix/bar", "CI_COMMIT_REF_NAME": "fix/bar"},
+			expected: "fix/bar",
+		},
+		"gitlab tag": {
+			env:      map[string]string{"CI_COMMIT_REF_NAME": "v1.2.3", "CI_COMMIT_TAG": "v1.2.3"},
+			expected: "",
+		},
+		"buildkite": {
+			env:      map[string]string{"BUILDKITE_BRANCH": "develop"},
+			expected: "develop",
+		},
+		"jenkins pull request": {
+			env:      map[string]string{"BRANCH_NAME": "PR-12", "CHANGE_BRANCH": "feature/x"},
+			expected: "feature/x",
+		},
+		"jenkins git plugin": {
+			env:      map[string]string{"GIT_BRANCH": "origin/release/1.x"},
+			expected: "release/1.x",
+		},
+		"azure": {
+			env:      map[string]string{"BUILD_SOURCEBRANCH": "refs/heads/hotfix/urgent"},
+			expected: "hotfix/urgent",
+		},
+		"azure pull request ref": {
+			env:      map[string]string{"BUILD_SOURCEBRANCH": "refs/pull/12/merge"},
+			expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.expected, branchFromEnv(func(key string) string {
+				return tt.env[key]
+			}))
+		})
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		clearCIEnv(tb)
+		tempdir(tb)
+		gitInit(tb)
+		gitCommit(tb, "chore: foobar")
+		for _, remote := range []string{"upstream", "origin", "origin/nested"} {
+			_, err := fakeGitRun("remote", "add", remote, "https://example.com/"+remote)
+			require.NoError(tb, err)
+		}
+	}
+
+	t.Run("ci variables win", func(t *testing.T) {
+		setup(t)
+		t.Setenv("BUILDKITE_BRANCH", "develop")
+		require.Equal(t, "develop", CurrentBranch(context.Background()))
+	})
+
+	t.Run("detached head prefers origin", func(t *testing.T) {
+		setup(t)
+		updateRef(t, "refs/remotes/upstream/aaa")
+		updateRef(t, "refs/remotes/origin/main")
+		updateRef(t, "refs/remotes/origin/zzz")
+		detach(t)
+		require.Equal(t, "main", CurrentBranch(context.Background()))
+	})
+
+	t.Run("detached head prefers branches pointing at it", func(t *testing.T) {
+		setup(t)
+		updateRef(t, "refs/remotes/origin/main")
+		gitCommit(t, "feat: foo")
+		updateRef(t, "refs/remotes/upstream/feature/foo")
+		detach(t)
+		require.Equal(t, "feature/foo", CurrentBranch(context.Background()))
+	})
+
+	t.Run("detached head with nested remote names", func(t *testing.T) {
+		setup(t)
+		updateRef(t, "refs/remotes/origin/nested/feature")
+		detach(t)
+		require.Equal(t, "feature", CurrentBranch(context.Background()))
+	})
+}
+
+func clearCIEnv(tb testing.TB) {
+	tb.Helper()
+	for _, name := range CIVariables() {
+		tb.Setenv(name, "")
+	}
+}
+
+func updateRef(tb testing.TB, ref string) {
+	_, err := fakeGitRun("update-ref", ref, "HEAD")
+	require.NoError(tb, err)
+}
+
+func detach(tb testing.TB) {
+	_, err := fakeGitRun("switch", "--detach", "HEAD")
+	require.NoError(tb, err)
+}
+
+func TestPullRequestFromEnv(t *testing.T) {
+	for name, tt := range map[string]struct {
+		env      map[string]string
+		expected *PullRequest
+	}{
+		"none": {
+			env: map[string]string{"GITHUB_REF": "refs/heads/main", "TRAVIS_PULL_REQUEST": "false"},
+		},
+		"github": {
+			env:      map[string]string{"GITHUB_REF": "refs/pull/482/merge", "GITHUB_BASE_REF": "main"},
+			expected: &PullRequest{Number: 482, Base: "main", MergeRef: true},
+		},
+		"gitlab": {
+			env:      map[string]string{"CI_MERGE_REQUEST_IID": "12", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "develop"},
+			expected: &PullRequest{Number: 12, Base: "develop"},
+		},
+		"jenkins": {
+			env:      map[string]string{"CHANGE_ID": "7", "CHANGE_TARGET": "master"},
+			expected: &PullRequest{Number: 7, Base: "master"},
+		},
+		"circleci": {
+			env:      map[string]string{"CIRCLE_PULL_REQUEST": "https://github.com/foo/bar/pull/33"},
+			expected: &PullRequest{Number: 33},
+		},
+		"azure": {
+			env:      map[string]string{"SYSTEM_PULLREQUEST_PULLREQUESTNUMBER": "5", "SYSTEM_PULLREQUEST_TARGETBRANCH": "refs/heads/main"},
+			expected: &PullRequest{Number: 5, Base: "main"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.expected, pullRequestFromEnv(func(key string) string {
+				return tt.env[key]
+			}))
+		})
+	}
+}
+
+func TestPullRequestFromRefs(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: foobar")
+	require.Nil(t, PullRequestFromRefs(context.Background()))
+
+	updateRef(t, "refs/remotes/origin/pull/9/head")
+	require.Equal(t, &PullRequest{Number: 9}, PullRequestFromRefs(context.Background()))
+
+	updateRef(t, "refs/pull/9/merge")
+	require.Equal(t, &PullRequest{Number: 9, MergeRef: true}, PullRequestFromRefs(context.Background()))
+}
+
+func TestCountCommits(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: foobar")
+	updateRef(t, "refs/remotes/origin/main")
+	gitCommit(t, "feat: foo")
+	gitCommit(t, "fix: bar")
+
+	base, err := ResolveBranch(context.Background(), "main")
+	require.NoError(t, err)
+	require.Equal(t, "refs/remotes/origin/main", base)
+
+	mergeBase, err := MergeBase(context.Background(), base, "HEAD")
+	require.NoError(t, err)
+
+	count, err := CountCommits(context.Background(), mergeBase, "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
+
+func TestBranchAt(t *testing.T) {
+	clearCIEnv(t)
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: first")
+	createBranch(t, "release/1.x")
+	gitCommit(t, "fix: on release")
+	switchToBranch(t, "-")
+	gitCommit(t, "feat: on main")
+	current := CurrentBranch(context.Background())
+
+	require.Equal(t, current, BranchAt(context.Background(), ""))
+	require.Equal(t, current, BranchAt(context.Background(), "HEAD"))
+	require.Equal(t, "release/1.x", BranchAt(context.Background(), "release/1.x"))
+	require.Equal(t, "release/1.x", BranchAt(context.Background(), "release/1.x~0"))
+	require.Equal(t, current, BranchAt(context.Background(), "HEAD~1"))
+
+	_, err := fakeGitRun("remote", "add", "origin", "https://example.com/origin")
+	require.NoError(t, err)
+	_, err = fakeGitRun("update-ref", "refs/remotes/origin/hotfix", "release/1.x")
+	require.NoError(t, err)
+	require.Equal(t, "hotfix", BranchAt(context.Background(), "origin/hotfix"))
+	require.Equal(t, "hotfix", BranchAt(context.Background(), "release/1.x~0"))
+
+	// Another branch containing rev doesn't win over the current or default
+	// one.
+	createBranch(t, "beta")
+	gitCommit(t, "feat: on beta")
+	switchToBranch(t, current)
+	require.Equal(t, current, BranchAt(context.Background(), current+"~0"))
+	switchToBranch(t, "beta")
+	require.Equal(t, "beta", BranchAt(context.Background(), current+"~0"))
+	switchToBranch(t, "release/1.x")
+	detach(t)
+	require.Equal(t, current, BranchAt(context.Background(), current+"~0"))
+}
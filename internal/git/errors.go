@@ -0,0 +1,55 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrGitMissing is returned when the git executable can't be found.
+	ErrGitMissing = errors.New("git executable not found in PATH")
+
+	// ErrNotRepository is returned when the current directory is not inside
+	// a git repository.
+	ErrNotRepository = errors.New("current directory is not a git repository")
+
+	// ErrShallowRepository is returned when the repository is a shallow clone
+	// and does not have the history needed to compute versions.
+	ErrShallowRepository = errors.New("repository is a shallow clone")
+)
+
+// CommandError is returned when a git command exits with a non-zero status.
+type CommandError struct {
+	// Args are the arguments git was run with.
+	Args []string
+	// Stderr is what git printed on its standard error.
+	Stderr   string
+	ExitCode int
+}
+
+func (e *CommandError) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("git %s: %s", strings.Join(e.Args, " "), msg)
+}
+
+// Is makes errors.Is(err, ErrNotRepository) true for commands which failed
+// because they were run outside of a repository.
+func (e *CommandError) Is(target error) bool {
+	return target == ErrNotRepository && strings.Contains(e.Stderr, "not a git repository")
+}
+
+// NoMatchError is returned when there are tags, but none of them match the
+// pattern they were filtered with.
+type NoMatchError struct {
+	Pattern string
+	// Kind describes the tags that were looked for, e.g. "stable tags".
+	Kind string
+}
+
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("no %s match '%s'", e.Kind, e.Pattern)
+}
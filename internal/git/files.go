@@ -0,0 +1,25 @@
+package git
+
+import (
+	"context"
+	"strings"
+)
+
+// ListFiles returns the paths of the files of rev, relative to the root of the
+// repository. Like the other functions reading history, paths restrict them
+// relatively to the current directory, which restricts them itself when there
+// are none.
+func ListFiles(ctx context.Context, rev string, paths []string) ([]string, error) {
+	args := append([]string{"ls-tree", "-r", "-z", "--name-only", "--full-name", rev, "--"}, paths...)
+	out, err := run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,31 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	require.NoError(t, os.MkdirAll("sub dir", 0o755))
+	require.NoError(t, os.WriteFile("a.txt", []byte("first"), 0o644))
+	require.NoError(t, os.WriteFile("sub dir/b.txt", []byte("nested"), 0o644))
+	gitAdd(t, ".")
+	gitCommit(t, "chore: first")
+	gitTag(t, "v1.0.0")
+	require.NoError(t, os.WriteFile("a.txt", []byte("second"), 0o644))
+	gitCommit(t, "chore: second")
+
+	ctx := context.Background()
+	files, err := ListFiles(ctx, "v1.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "sub dir/b.txt"}, files)
+
+	files, err = ListFiles(ctx, "v1.0.0", []string{"sub dir"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"sub dir/b.txt"}, files)
+}
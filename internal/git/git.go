@@ -1,23 +1,50 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gobwas/glob"
 )
 
-// Commit is a commit with a hash, title (first line of the message), and body
-// (rest of the message, not including the title).
+// Commit is a commit with a hash, title (first line of the message), body
+// (rest of the message, not including the title), author and committer.
+// Names and emails are mapped through the repository's .mailmap.
 type Commit struct {
-	SHA   string
-	Title string
-	Body  string
+	SHA            string
+	Title          string
+	Body           string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitDate     time.Time
+	// CoAuthors are the people credited with Co-authored-by trailers.
+	CoAuthors []Person
+	// Parents are the hashes of the parent commits, more than one for merges.
+	Parents []string
 }
 
+// Person is the name and email of an author, committer or co-author.
+type Person struct {
+	Name  string
+	Email string
+}
+
+var coAuthoredBy = regexp.MustCompile(`(?im)^co-authored-by:[ \t]*(.*?)[ \t]*<([^>]*)>[ \t]*$`)
+
 func (c Commit) String() string {
 	return c.SHA + ": " + c.Title + "\n" + c.Body
 }
@@ -28,12 +55,8 @@ const (
 )
 
 // GetLatestTagWithSuffix returns the latest tag with the specified suffix
-func GetLatestTagWithSuffix(suffix string, tagMode string, pattern string) (string, error) {
-	args := []string{}
-	if tagMode == TagModeCurrent {
-		args = []string{"--merged"}
-	}
-	tags, err := getAllTags(args...)
+func GetLatestTagWithSuffix(ctx context.Context, suffix string, tagMode string, rev string, prefix string, pattern string) (string, error) {
+	tags, err := getAllTags(ctx, prefix, tagModeArgs(tagMode, rev)...)
 	if err != nil {
 		return "", err
 	}
@@ -65,7 +88,7 @@ func GetLatestTagWithSuffix(suffix string, tagMode string, pattern string) (stri
 				return tag, nil
 			}
 		}
-		return "", fmt.Errorf("no tags with suffix '%s' match pattern '%s'", suffix, pattern)
+		return "", &NoMatchError{Pattern: pattern, Kind: fmt.Sprintf("tags with suffix '%s'", suffix)}
 	}
 
 	return suffixTags[0], nil
@@ -101,39 +124,99 @@ func isDigit(c byte) bool {
 // copied from goreleaser
 
 // IsRepo returns true if current folder is a git repository
-func IsRepo() bool {
-	out, err := run("rev-parse", "--is-inside-work-tree")
-	return err == nil && strings.TrimSpace(out) == "true"
+func IsRepo(ctx context.Context) bool {
+	return EnsureRepo(ctx) == nil
+}
+
+// EnsureRepo returns ErrNotRepository if the current folder is not a git
+// repository, or ErrGitMissing if git is not installed.
+func EnsureRepo(ctx context.Context) error {
+	out, err := run(ctx, "rev-parse", "--is-inside-work-tree")
+	if errors.Is(err, ErrNotRepository) {
+		return ErrNotRepository
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(out) != "true" {
+		return ErrNotRepository
+	}
+	return nil
+}
+
+// IsShallow returns true if the current repository is a shallow clone
+func IsShallow(ctx context.Context) (bool, error) {
+	out, err := run(ctx, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "true", nil
 }
 
-func Root() string {
-	out, _ := run("rev-parse", "--show-toplevel")
+// Deepen fetches more history and tags from the default remote until reached
+// returns true, doubling the depth on each attempt. If the limit is hit
+// before that, the rest of the history is fetched at once.
+func Deepen(ctx context.Context, reached func() (bool, error)) error {
+	const (
+		initialDepth = 50
+		maxDepth     = 6400
+	)
+
+	for depth := initialDepth; ; depth *= 2 {
+		ok, err := reached()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		shallow, err := IsShallow(ctx)
+		if err != nil {
+			return err
+		}
+		if !shallow {
+			return nil
+		}
+
+		if depth > maxDepth {
+			if _, err := run(ctx, "fetch", "--tags", "--unshallow"); err != nil {
+				return fmt.Errorf("failed to unshallow repository: %w", err)
+			}
+			return nil
+		}
+
+		if _, err := run(ctx, "fetch", "--tags", "--deepen="+strconv.Itoa(depth)); err != nil {
+			return fmt.Errorf("failed to deepen repository: %w", err)
+		}
+	}
+}
+
+func Root(ctx context.Context) string {
+	out, _ := run(ctx, "rev-parse", "--show-toplevel")
 	return strings.TrimSpace(out)
 }
 
-// CurrentBranch returns the current branch name
-func CurrentBranch() string {
+// CurrentBranch returns the current branch name. The branch exposed by a known
+// CI system takes precedence, as CI checkouts are usually in detached HEAD.
+func CurrentBranch(ctx context.Context) string {
+	if branch := branchFromCI(); branch != "" {
+		return branch
+	}
+
 	// First try to get the current branch name
-	out, err := run("rev-parse", "--abbrev-ref", "HEAD")
+	out, err := run(ctx, "rev-parse", "--abbrev-ref", "HEAD")
 	if err == nil && strings.TrimSpace(out) != "HEAD" {
 		return strings.TrimSpace(out)
 	}
 
 	// If we're in detached HEAD state, try to find the branch containing HEAD
-	out, err = run("branch", "-r", "--contains", "HEAD")
-	if err == nil && out != "" {
-		lines := strings.Split(strings.TrimSpace(out), "\n")
-		if len(lines) > 0 {
-			// Take the first remote branch and clean it up
-			branch := strings.TrimSpace(lines[0])
-			// Remove "origin/" prefix if present
-			branch = strings.TrimPrefix(branch, "origin/")
-			return branch
-		}
+	if branch := remoteBranchContaining(ctx, "HEAD"); branch != "" {
+		return branch
 	}
 
 	// Fallback: try to get branch from git symbolic-ref
-	out, err = run("symbolic-ref", "--short", "HEAD")
+	out, err = run(ctx, "symbolic-ref", "--short", "HEAD")
 	if err == nil {
 		return strings.TrimSpace(out)
 	}
@@ -142,20 +225,197 @@ func CurrentBranch() string {
 	return "HEAD"
 }
 
-func getAllTags(args ...string) ([]string, error) {
-	tags, err := run(append([]string{"-c", "versionsort.suffix=-", "tag", "--sort=-version:refname"}, args...)...)
+// BranchAt returns the branch of a revision: the current branch for HEAD (or
+// an empty rev), the branch itself when rev names one, or else a branch
+// containing it: the current branch, then the default one, then any other,
+// remote branches first. It returns rev when none does.
+func BranchAt(ctx context.Context, rev string) string {
+	if rev == "" || rev == "HEAD" {
+		return CurrentBranch(ctx)
+	}
+	if _, err := run(ctx, "show-ref", "--verify", "--quiet", "refs/heads/"+rev); err == nil {
+		return rev
+	}
+	if _, err := run(ctx, "show-ref", "--verify", "--quiet", "refs/remotes/"+rev); err == nil {
+		if _, branch, ok := strings.Cut(rev, "/"); ok {
+			return branch
+		}
+	}
+	if branch := CurrentBranch(ctx); branch != "HEAD" && isAncestor(ctx, rev, "HEAD") {
+		return branch
+	}
+	for _, branch := range []string{DefaultBranch(ctx), "main", "master"} {
+		if branch == "" {
+			continue
+		}
+		if ref, err := ResolveBranch(ctx, branch); err == nil && isAncestor(ctx, rev, ref) {
+			return branch
+		}
+	}
+	if branch := remoteBranchContaining(ctx, rev); branch != "" {
+		return branch
+	}
+	for _, filter := range []string{"--points-at", "--contains"} {
+		out, err := run(ctx, "branch", filter, rev, "--format=%(refname:short)")
+		if err != nil {
+			continue
+		}
+		if branches := strings.Fields(out); len(branches) > 0 {
+			sort.Strings(branches)
+			return branches[0]
+		}
+	}
+	return rev
+}
+
+// isAncestor reports whether rev is reachable from ref.
+func isAncestor(ctx context.Context, rev, ref string) bool {
+	_, err := run(ctx, "merge-base", "--is-ancestor", rev, ref)
+	return err == nil
+}
+
+// remoteBranchContaining picks a remote branch containing rev and returns its
+// name without the remote. Branches pointing at rev are preferred over the
+// ones merely containing it, and "origin" over the other remotes; ties are
+// broken by name so the result doesn't depend on git's output order.
+func remoteBranchContaining(ctx context.Context, rev string) string {
+	remotesOut, err := run(ctx, "remote")
 	if err != nil {
-		return nil, err
+		return ""
+	}
+	remotes := strings.Fields(remotesOut)
+	// Longest first, so "origin/foo" wins over "origin" for nested names.
+	sort.SliceStable(remotes, func(i, j int) bool { return len(remotes[i]) > len(remotes[j]) })
+
+	for _, filter := range []string{"--points-at", "--contains"} {
+		out, err := run(ctx, "branch", "-r", filter, rev, "--format=%(refname:short)%09%(symref)")
+		if err != nil {
+			continue
+		}
+
+		var candidates [][2]string
+		for _, line := range strings.Split(out, "\n") {
+			ref, symref, _ := strings.Cut(line, "\t")
+			ref = strings.TrimSpace(ref)
+			if ref == "" || strings.TrimSpace(symref) != "" {
+				continue
+			}
+			for _, remote := range remotes {
+				if branch, ok := strings.CutPrefix(ref, remote+"/"); ok {
+					candidates = append(candidates, [2]string{remote, branch})
+					break
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if (a[0] == "origin") != (b[0] == "origin") {
+				return a[0] == "origin"
+			}
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			return a[1] < b[1]
+		})
+		return candidates[0][1]
+	}
+	return ""
+}
+
+// DefaultBranch returns the branch origin/HEAD points to, if known
+func DefaultBranch(ctx context.Context) string {
+	out, err := run(ctx, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(out), "origin/")
+}
+
+// ResolveBranch returns the ref to use for the given branch name, preferring
+// its remote-tracking branch on origin, which is what CI checkouts have.
+func ResolveBranch(ctx context.Context, branch string) (string, error) {
+	for _, ref := range []string{"refs/remotes/origin/" + branch, "refs/heads/" + branch} {
+		if _, err := run(ctx, "rev-parse", "--verify", "--quiet", ref); err == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("branch '%s' not found", branch)
+}
+
+// RemoteURL returns the URL of the given remote
+func RemoteURL(ctx context.Context, remote string) (string, error) {
+	out, err := run(ctx, "remote", "get-url", remote)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// MergeBase returns the best common ancestor of the two revisions
+func MergeBase(ctx context.Context, a, b string) (string, error) {
+	out, err := run(ctx, "merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CommitExists reports whether rev names a commit, e.g. whether HEAD^2 exists
+// because HEAD is a merge.
+func CommitExists(ctx context.Context, rev string) bool {
+	_, err := run(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	return err == nil
+}
+
+// CountCommits returns the number of commits reachable from rev but not from
+// base
+func CountCommits(ctx context.Context, base, rev string) (int, error) {
+	out, err := run(ctx, "rev-list", "--count", base+".."+rev)
+	if err != nil {
+		return 0, err
 	}
-	return strings.Split(tags, "\n"), nil
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// tagModeArgs returns the arguments of git tag listing the tags of a mode:
+// all of them, or the ones reachable from rev (HEAD when empty) for
+// TagModeCurrent.
+func tagModeArgs(tagMode string, rev string) []string {
+	switch {
+	case tagMode != TagModeCurrent:
+		return nil
+	case rev == "":
+		return []string{"--merged"}
+	}
+	return []string{"--merged", rev}
 }
 
-func DescribeTag(tagMode string, pattern string) (string, error) {
-	args := []string{}
-	if tagMode == TagModeCurrent {
-		args = []string{"--merged"}
+// getAllTags lists the tags in the repository sorted by descending SemVer
+// precedence. Sorting is done here rather than with git's version:refname,
+// as that one does not follow SemVer for pre-releases and build metadata.
+func getAllTags(ctx context.Context, prefix string, args ...string) ([]string, error) {
+	out, err := run(ctx, append([]string{"tag"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, tag := range strings.Split(out, "\n") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
 	}
-	tags, err := getAllTags(args...)
+	return sortTags(tags, prefix), nil
+}
+
+// DescribeTag returns the latest tag, pre-releases included. With
+// TagModeCurrent, only the tags reachable from rev (HEAD when empty) are
+// considered.
+func DescribeTag(ctx context.Context, tagMode string, rev string, prefix string, pattern string) (string, error) {
+	tags, err := getAllTags(ctx, prefix, tagModeArgs(tagMode, rev)...)
 	if err != nil {
 		return "", err
 	}
@@ -176,18 +436,15 @@ func DescribeTag(tagMode string, pattern string) (string, error) {
 			return tag, nil
 		}
 	}
-	return "", fmt.Errorf("no tags match '%s'", pattern)
+	return "", &NoMatchError{Pattern: pattern, Kind: "tags"}
 }
 
 // DescribeStableTag returns the latest stable (non-prerelease) tag from the main branch
 // This follows semantic-release standards where the base version should be the latest
-// stable release from the main branch, not the latest tag including prereleases
-func DescribeStableTag(tagMode string, pattern string) (string, error) {
-	args := []string{}
-	if tagMode == TagModeCurrent {
-		args = []string{"--merged"}
-	}
-	tags, err := getAllTags(args...)
+// stable release from the main branch, not the latest tag including prereleases.
+// With TagModeCurrent, only the tags reachable from rev (HEAD when empty) are considered.
+func DescribeStableTag(ctx context.Context, tagMode string, rev string, prefix string, pattern string) (string, error) {
+	tags, err := getAllTags(ctx, prefix, tagModeArgs(tagMode, rev)...)
 	if err != nil {
 		return "", err
 	}
@@ -196,11 +453,30 @@ func DescribeStableTag(tagMode string, pattern string) (string, error) {
 		return "", nil
 	}
 
+	return firstStableMatch(tags, pattern, "stable tags")
+}
+
+// PreviousStableTag returns the latest stable tag reachable from tag, not
+// counting the ones on the same commit, or an empty string when there is none.
+func PreviousStableTag(ctx context.Context, tag string, prefix string, pattern string) (string, error) {
+	tags, err := getAllTags(ctx, prefix, "--merged", "tags/"+tag, "--no-contains", "tags/"+tag)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", nil
+	}
+	return firstStableMatch(tags, pattern, "previous stable tags")
+}
+
+// firstStableMatch returns the first stable tag matching the pattern, which
+// is ignored when empty.
+func firstStableMatch(tags []string, pattern string, kind string) (string, error) {
 	// Filter out prerelease tags (tags with suffixes like -alpha, -beta, -rc, etc.)
 	var stableTags []string
 	for _, tag := range tags {
 		// Check if tag is a stable release (no prerelease suffix)
-		if isStableTag(tag) {
+		if IsStableTag(tag) {
 			stableTags = append(stableTags, tag)
 		}
 	}
@@ -222,11 +498,11 @@ func DescribeStableTag(tagMode string, pattern string) (string, error) {
 			return tag, nil
 		}
 	}
-	return "", fmt.Errorf("no stable tags match '%s'", pattern)
+	return "", &NoMatchError{Pattern: pattern, Kind: kind}
 }
 
-// isStableTag checks if a tag represents a stable release (no prerelease suffix)
-func isStableTag(tag string) bool {
+// IsStableTag checks if a tag represents a stable release (no prerelease suffix)
+func IsStableTag(tag string) bool {
 	// Remove common prefixes like 'v'
 	tag = strings.TrimPrefix(tag, "v")
 
@@ -253,57 +529,269 @@ func isStableTag(tag string) bool {
 	return true
 }
 
-func Changelog(tag string, dirs []string) ([]Commit, error) {
-	if tag == "" {
-		return gitLog(dirs, "HEAD")
-	} else {
-		return gitLog(dirs, fmt.Sprintf("tags/%s..HEAD", tag))
+func Changelog(ctx context.Context, tag string, dirs []string) ([]Commit, error) {
+	return ChangelogBetween(ctx, tag, "HEAD", dirs, false)
+}
+
+// ChangelogBetween returns the commits reachable from the rev but not from
+// the tag, or all the commits reachable from the rev when tag is empty. With
+// firstParent, only the first parent of merge commits is followed, leaving
+// out the commits of merged branches.
+func ChangelogBetween(ctx context.Context, tag string, rev string, dirs []string, firstParent bool) ([]Commit, error) {
+	return gitLog(ctx, dirs, logRefs(tag, rev, firstParent)...)
+}
+
+// FirstParentLine returns the commits of the first-parent line of rev, oldest
+// first, not reachable from base, or all of them when base is empty.
+func FirstParentLine(ctx context.Context, base string, rev string) ([]Commit, error) {
+	if base != "" {
+		rev = base + ".." + rev
 	}
+	return gitLog(ctx, nil, "--first-parent", "--reverse", rev)
 }
 
-func run(args ...string) (string, error) {
-	extraArgs := []string{
-		"-c", "log.showSignature=false",
+// LogOptions selects the commits read by Log.
+type LogOptions struct {
+	// Paths limits the commits to the ones touching these paths.
+	Paths []string
+	// FirstParent only follows the first parent of merge commits.
+	FirstParent bool
+}
+
+// Log returns an iterator over the commits ChangelogBetween would return,
+// newest first. They are read from git as the iteration goes, so breaking out
+// of it early stops reading the history. An error ends the iteration, yielded
+// with a zero Commit. Unlike ChangelogBetween, co-authors are not mapped
+// through the .mailmap.
+func Log(ctx context.Context, tag string, rev string, opts LogOptions) iter.Seq2[Commit, error] {
+	refs := logRefs(tag, rev, opts.FirstParent)
+	return func(yield func(Commit, error) bool) {
+		if err := walkLog(ctx, opts.Paths, refs, func(commit Commit) bool {
+			return yield(commit, nil)
+		}); err != nil {
+			yield(Commit{}, err)
+		}
+	}
+}
+
+func logRefs(tag string, rev string, firstParent bool) []string {
+	refs := []string{rev}
+	if tag != "" {
+		refs = []string{fmt.Sprintf("tags/%s..%s", tag, rev)}
 	}
-	args = append(extraArgs, args...)
+	if firstParent {
+		refs = append([]string{"--first-parent"}, refs...)
+	}
+	return refs
+}
+
+var extraArgs = []string{
+	"-c", "log.showSignature=false",
+}
+
+func run(ctx context.Context, args ...string) (string, error) {
+	return runInput(ctx, "", args...)
+}
+
+// runInput is run with input written to the standard input of git.
+func runInput(ctx context.Context, input string, args ...string) (string, error) {
 	/* #nosec */
-	cmd := exec.Command("git", args...)
-	bts, err := cmd.CombinedOutput()
+	cmd := exec.CommandContext(ctx, "git", append(extraArgs, args...)...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", commandError(ctx, args, err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// errStopReading is returned by the read function of stream to stop early.
+var errStopReading = errors.New("stop reading")
+
+// stream runs git with its standard output handed to read as it is produced,
+// its standard error being captured apart for errors. When read returns
+// errStopReading, git is killed and stream returns nil.
+func stream(ctx context.Context, read func(io.Reader) error, args ...string) error {
+	gitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	/* #nosec */
+	cmd := exec.CommandContext(gitCtx, "git", append(extraArgs, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return "", errors.New(string(bts))
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return commandError(ctx, args, err, stderr.String())
+	}
+
+	readErr := read(stdout)
+	if errors.Is(readErr, errStopReading) {
+		cancel()
+		_ = cmd.Wait()
+		return nil
 	}
-	return string(bts), nil
+	// Let git finish writing, if read didn't consume everything.
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return commandError(ctx, args, err, stderr.String())
+	}
+	return readErr
+}
+
+// commandError turns the error of running git into one of the errors of this
+// package.
+func commandError(ctx context.Context, args []string, err error, stderr string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrGitMissing
+	}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return &CommandError{Args: args, Stderr: stderr, ExitCode: exitCode}
 }
 
-func gitLog(dirs []string, refs ...string) ([]Commit, error) {
-	args := []string{"log", "--no-decorate", "--no-color", `--format=%H:%B<svu-commit-end>`}
+// logFormat separates the fields of commits with NULs, which can't appear in
+// commit messages; with -z, commits are separated by NULs too.
+const logFormat = `--format=%H%x00%P%x00%aN%x00%aE%x00%aI%x00%cN%x00%cE%x00%cI%x00%B`
+
+// logFields is the number of fields of logFormat.
+const logFields = 9
+
+func gitLog(ctx context.Context, dirs []string, refs ...string) ([]Commit, error) {
+	var result []Commit
+	if err := walkLog(ctx, dirs, refs, func(commit Commit) bool {
+		result = append(result, commit)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	if err := mapCoAuthors(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkLog runs git log, calling fn with each commit as it is read until fn
+// returns false.
+func walkLog(ctx context.Context, dirs []string, refs []string, fn func(Commit) bool) error {
+	args := []string{"log", "--no-decorate", "--no-color", "-z", logFormat}
 	args = append(args, refs...)
 	if len(dirs) > 0 {
 		args = append(args, "--")
 		args = append(args, dirs...)
 	}
-	s, err := run(args...)
-	if err != nil {
-		return nil, err
-	}
-	var result []Commit
-	for _, commit := range strings.Split(s, "<svu-commit-end>") {
-		commit = strings.TrimSpace(commit)
-		if commit == "" { // accounts for the last split, which will be an empty line
+	return stream(ctx, func(r io.Reader) error {
+		return readLog(r, fn)
+	}, args...)
+}
+
+// readLog parses the output of git log with logFormat.
+func readLog(r io.Reader, fn func(Commit) bool) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	fields := make([]string, 0, logFields)
+	for {
+		field, err := br.ReadString(0)
+		if err == io.EOF {
+			if len(fields) > 0 || field != "" {
+				return fmt.Errorf("truncated git log output: %d fields of %d read", len(fields)+1, logFields)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields = append(fields, strings.TrimSuffix(field, "\x00"))
+		if len(fields) < logFields {
 			continue
 		}
+		if !fn(parseCommit(fields)) {
+			return errStopReading
+		}
+		fields = fields[:0]
+	}
+}
 
-		hashEndIdx := strings.Index(commit, ":")
-		titleEndIdx := strings.Index(commit, "\n")
-		if titleEndIdx < 0 {
-			titleEndIdx = len(commit)
+// parseCommit parses the fields of a commit formatted with logFormat.
+func parseCommit(fields []string) Commit {
+	title, body, _ := strings.Cut(strings.TrimSpace(fields[8]), "\n")
+	authorDate, _ := time.Parse(time.RFC3339, fields[4])
+	commitDate, _ := time.Parse(time.RFC3339, fields[7])
+
+	commit := Commit{
+		SHA:            fields[0],
+		Title:          title,
+		Body:           body,
+		AuthorName:     fields[2],
+		AuthorEmail:    fields[3],
+		AuthorDate:     authorDate,
+		CommitterName:  fields[5],
+		CommitterEmail: fields[6],
+		CommitDate:     commitDate,
+		Parents:        strings.Fields(fields[1]),
+	}
+	for _, m := range coAuthoredBy.FindAllStringSubmatch(body, -1) {
+		commit.CoAuthors = append(commit.CoAuthors, Person{Name: m[1], Email: m[2]})
+	}
+	return commit
+}
+
+// mapCoAuthors maps the co-authors through the .mailmap, which git only
+// applies to authors and committers.
+func mapCoAuthors(ctx context.Context, commits []Commit) error {
+	var contacts []string
+	seen := map[string]bool{}
+	for _, commit := range commits {
+		for _, p := range commit.CoAuthors {
+			if contact := p.contact(); !seen[contact] {
+				seen[contact] = true
+				contacts = append(contacts, contact)
+			}
 		}
+	}
+	if len(contacts) == 0 {
+		return nil
+	}
 
-		result = append(result, Commit{
-			commit[:hashEndIdx],
-			commit[hashEndIdx+1 : titleEndIdx],
-			commit[min(titleEndIdx+1, len(commit)):],
-		})
+	// Contacts are read from stdin, as arguments they could be taken for
+	// options or exceed the length of command lines.
+	out, err := runInput(ctx, strings.Join(contacts, "\n")+"\n", "check-mailmap", "--stdin")
+	if err != nil {
+		return err
 	}
-	return result, nil
+	mapped := map[string]Person{}
+	for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if i >= len(contacts) {
+			break
+		}
+		if m := contactPattern.FindStringSubmatch(line); m != nil {
+			mapped[contacts[i]] = Person{Name: m[1], Email: m[2]}
+		}
+	}
+	for _, commit := range commits {
+		for j, p := range commit.CoAuthors {
+			if m, ok := mapped[p.contact()]; ok {
+				commit.CoAuthors[j] = m
+			}
+		}
+	}
+	return nil
+}
+
+var contactPattern = regexp.MustCompile(`^(.*?)\s*<([^>]*)>$`)
+
+// contact formats the person as git does, "Name <email>".
+func (p Person) contact() string {
+	return p.Name + " <" + p.Email + ">"
 }
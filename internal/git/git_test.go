@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"os"
 	"path"
 	"testing"
@@ -12,13 +13,40 @@ import (
 func TestIsRepo(t *testing.T) {
 	t.Run("is not a repo", func(t *testing.T) {
 		tempdir(t)
-		require.False(t, IsRepo()) // should not be arepo
+		require.False(t, IsRepo(context.Background())) // should not be arepo
 	})
 
 	t.Run("is a repo", func(t *testing.T) {
 		tempdir(t)
 		gitInit(t)
-		require.True(t, IsRepo()) // should be arepo
+		require.True(t, IsRepo(context.Background())) // should be arepo
+	})
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Run("not a repo", func(t *testing.T) {
+		tempdir(t)
+		_, err := run(context.Background(), "tag")
+		require.ErrorIs(t, err, ErrNotRepository)
+		require.ErrorIs(t, EnsureRepo(context.Background()), ErrNotRepository)
+	})
+
+	t.Run("command failed", func(t *testing.T) {
+		tempdir(t)
+		gitInit(t)
+		_, err := run(context.Background(), "rev-parse", "--verify", "refs/tags/nope")
+		var cmdErr *CommandError
+		require.ErrorAs(t, err, &cmdErr)
+		require.Equal(t, []string{"rev-parse", "--verify", "refs/tags/nope"}, cmdErr.Args)
+		require.Equal(t, 128, cmdErr.ExitCode)
+		require.Contains(t, cmdErr.Stderr, "Needed a single revision")
+		require.NotErrorIs(t, err, ErrNotRepository)
+	})
+
+	t.Run("git missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		_, err := run(context.Background(), "version")
+		require.ErrorIs(t, err, ErrGitMissing)
 	})
 }
 
@@ -45,24 +73,52 @@ func TestDescribeTag(t *testing.T) {
 	}
 	t.Run(TagModeCurrent, func(t *testing.T) {
 		setup(t)
-		tag, err := DescribeTag(TagModeCurrent, "")
+		tag, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "")
 		require.NoError(t, err)
 		require.Equal(t, "v1.2.4", tag)
 	})
 
 	t.Run(TagModeAll, func(t *testing.T) {
 		setup(t)
-		tag, err := DescribeTag(TagModeAll, "")
+		tag, err := DescribeTag(context.Background(), TagModeAll, "", "", "")
 		require.NoError(t, err)
 		require.Equal(t, "v1.2.5", tag)
 	})
 
 	t.Run("pattern", func(t *testing.T) {
 		setup(t)
-		tag, err := DescribeTag(TagModeCurrent, "pattern-*")
+		tag, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "pattern-*")
 		require.NoError(t, err)
 		require.Equal(t, "pattern-1.2.3", tag)
 	})
+
+	t.Run("pattern without match", func(t *testing.T) {
+		setup(t)
+		_, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "nope-*")
+		var noMatch *NoMatchError
+		require.ErrorAs(t, err, &noMatch)
+		require.EqualError(t, err, "no tags match 'nope-*'")
+	})
+}
+
+func TestPreviousStableTag(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: foobar")
+	gitTag(t, "v1.2.3")
+	gitCommit(t, "fix: foo")
+	gitTag(t, "v1.2.4-rc.1")
+	gitCommit(t, "feat: bar")
+	gitTag(t, "v1.3.0")
+	gitTag(t, "v1.3.0-also")
+
+	tag, err := PreviousStableTag(context.Background(), "v1.3.0", "v", "")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", tag)
+
+	tag, err = PreviousStableTag(context.Background(), "v1.2.3", "v", "")
+	require.NoError(t, err)
+	require.Empty(t, tag)
 }
 
 func TestChangelog(t *testing.T) {
@@ -78,7 +134,7 @@ func TestChangelog(t *testing.T) {
 	} {
 		gitCommit(t, msg)
 	}
-	log, err := Changelog("v1.2.3", nil)
+	log, err := Changelog(context.Background(), "v1.2.3", nil)
 	require.NoError(t, err)
 	for _, title := range []string{
 		"chore: foobar",
@@ -89,6 +145,91 @@ func TestChangelog(t *testing.T) {
 	}
 }
 
+func TestChangelogAttribution(t *testing.T) {
+	dir := tempdir(t)
+	gitInit(t)
+	require.NoError(t, os.WriteFile(path.Join(dir, ".mailmap"), []byte("Robert <bob@example.com> <bob@old.example.com>\n"), 0o644))
+	gitAdd(t, ".mailmap")
+	_, err := fakeGitRun("commit", "-m", "feat: foo", "-m", "Co-authored-by: Bobby <bob@old.example.com>\nco-authored-by: Carol <carol@example.com>\nCo-authored-by: -v <dash@example.com>")
+	require.NoError(t, err)
+
+	log, err := Changelog(context.Background(), "", nil)
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	commit := log[0]
+	require.Equal(t, "feat: foo", commit.Title)
+	require.Equal(t, "svu", commit.AuthorName)
+	require.Equal(t, "svu", commit.CommitterName)
+	require.Equal(t, "svu@example.com", commit.CommitterEmail)
+	require.WithinDuration(t, time.Now(), commit.AuthorDate, time.Minute)
+	require.WithinDuration(t, time.Now(), commit.CommitDate, time.Minute)
+	require.Equal(t, []Person{
+		{Name: "Robert", Email: "bob@example.com"},
+		{Name: "Carol", Email: "carol@example.com"},
+		{Name: "-v", Email: "dash@example.com"},
+	}, commit.CoAuthors)
+}
+
+func TestChangelogMessages(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "fix: keep <svu-commit-end> as is\n\nbody: with colons\n<svu-commit-end>\n\nand paragraphs")
+	gitCommit(t, "  feat: title: with colon  ")
+
+	log, err := Changelog(context.Background(), "", nil)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	require.Equal(t, "feat: title: with colon", log[0].Title)
+	require.Empty(t, log[0].Body)
+	require.Equal(t, "fix: keep <svu-commit-end> as is", log[1].Title)
+	require.Equal(t, "\nbody: with colons\n<svu-commit-end>\n\nand paragraphs", log[1].Body)
+	require.Equal(t, []string{log[1].SHA}, log[0].Parents)
+	require.Empty(t, log[1].Parents)
+}
+
+func TestLog(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: first")
+	gitTag(t, "v1.0.0")
+	for _, msg := range []string{"fix: a", "feat: b", "chore: c\n\nRelease-As: 2.0.0", "fix: d"} {
+		gitCommit(t, msg)
+	}
+
+	titles := func(opts LogOptions, max int) []string {
+		t.Helper()
+		var titles []string
+		for commit, err := range Log(context.Background(), "v1.0.0", "HEAD", opts) {
+			require.NoError(t, err)
+			titles = append(titles, commit.Title)
+			if len(titles) == max {
+				break
+			}
+		}
+		return titles
+	}
+
+	t.Run("all", func(t *testing.T) {
+		require.Equal(t, []string{"fix: d", "chore: c", "feat: b", "fix: a"}, titles(LogOptions{}, 100))
+	})
+
+	t.Run("stops early", func(t *testing.T) {
+		require.Equal(t, []string{"fix: d", "chore: c"}, titles(LogOptions{}, 2))
+	})
+
+	t.Run("command failed", func(t *testing.T) {
+		var errs []error
+		for _, err := range Log(context.Background(), "nope", "HEAD", LogOptions{}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		var cmdErr *CommandError
+		require.ErrorAs(t, errs[0], &cmdErr)
+		require.Equal(t, 128, cmdErr.ExitCode)
+		require.Contains(t, cmdErr.Stderr, "tags/nope")
+	})
+}
+
 func requireLogContains(tb testing.TB, log []Commit, title string) {
 	tb.Helper()
 	for _, commit := range log {
@@ -119,13 +260,66 @@ func TestChangelogWithDirectory(t *testing.T) {
 	gitCommit(t, "feat: foobar")
 	gitAdd(t, file)
 	gitCommit(t, "chore: filtered dir")
-	log, err := Changelog("v1.2.3", []string{localDir})
+	log, err := Changelog(context.Background(), "v1.2.3", []string{localDir})
 	require.NoError(t, err)
 
 	requireLogContains(t, log, "chore: filtered dir")
 	requireLogNotContains(t, log, "feat: foobar")
 }
 
+func TestShallow(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		origin := tempdir(tb)
+		gitInit(tb)
+		gitCommit(tb, "chore: foobar")
+		gitTag(tb, "v1.2.3")
+		for range 120 {
+			gitCommit(tb, "fix: foo")
+		}
+		clone := tb.TempDir()
+		_, err := fakeGitRun("clone", "--depth", "1", "file://"+origin, clone)
+		require.NoError(tb, err)
+		require.NoError(tb, os.Chdir(clone))
+	}
+
+	t.Run("detects shallow clones", func(t *testing.T) {
+		setup(t)
+		shallow, err := IsShallow(context.Background())
+		require.NoError(t, err)
+		require.True(t, shallow)
+
+		tag, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "")
+		require.NoError(t, err)
+		require.Empty(t, tag)
+	})
+
+	t.Run("deepens until the tag is reachable", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, Deepen(context.Background(), func() (bool, error) {
+			tag, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "")
+			return tag != "", err
+		}))
+
+		tag, err := DescribeTag(context.Background(), TagModeCurrent, "", "", "")
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.3", tag)
+
+		log, err := Changelog(context.Background(), tag, nil)
+		require.NoError(t, err)
+		require.Len(t, log, 120)
+	})
+
+	t.Run("unshallows when nothing is ever reached", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, Deepen(context.Background(), func() (bool, error) { return false, nil }))
+
+		shallow, err := IsShallow(context.Background())
+		require.NoError(t, err)
+		require.False(t, shallow)
+	})
+}
+
 func switchToBranch(tb testing.TB, branch string) {
 	_, err := fakeGitRun("switch", branch)
 	require.NoError(tb, err)
@@ -192,5 +386,5 @@ func fakeGitRun(args ...string) (string, error) {
 		"-c", "log.showSignature=false",
 	}
 	allArgs = append(allArgs, args...)
-	return run(allArgs...)
+	return run(context.Background(), allArgs...)
 }
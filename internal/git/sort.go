@@ -0,0 +1,49 @@
+package git
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// sortTags sorts tags in descending SemVer precedence, after removing the
+// given prefix. Tags with equal precedence (e.g. differing only in build
+// metadata) are ordered by name, descending, so the result is deterministic.
+// Tags that are not valid versions are kept, sorted by name, descending, after
+// all the valid ones.
+func sortTags(tags []string, prefix string) []string {
+	type parsedTag struct {
+		name    string
+		version *semver.Version
+	}
+
+	parsed := make([]parsedTag, 0, len(tags))
+	for _, tag := range tags {
+		version, err := semver.NewVersion(strings.TrimPrefix(tag, prefix))
+		if err != nil {
+			version = nil
+		}
+		parsed = append(parsed, parsedTag{name: tag, version: version})
+	}
+
+	slices.SortStableFunc(parsed, func(a, b parsedTag) int {
+		switch {
+		case a.version != nil && b.version == nil:
+			return -1
+		case a.version == nil && b.version != nil:
+			return 1
+		case a.version != nil && b.version != nil:
+			if c := b.version.Compare(a.version); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(b.name, a.name)
+	})
+
+	result := make([]string, 0, len(parsed))
+	for _, tag := range parsed {
+		result = append(result, tag.name)
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package git
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortTags(t *testing.T) {
+	t.Run("numeric pre-release identifiers", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"v1.0.0", "v1.0.0-rc.10", "v1.0.0-rc.9", "v1.0.0-rc.2"},
+			sortTags([]string{"v1.0.0-rc.2", "v1.0.0-rc.10", "v1.0.0", "v1.0.0-rc.9"}, "v"),
+		)
+	})
+
+	t.Run("spec example", func(t *testing.T) {
+		// https://semver.org/#spec-item-11
+		require.Equal(t,
+			[]string{
+				"1.0.0",
+				"1.0.0-rc.1",
+				"1.0.0-beta.11",
+				"1.0.0-beta.2",
+				"1.0.0-beta",
+				"1.0.0-alpha.beta",
+				"1.0.0-alpha.1",
+				"1.0.0-alpha",
+			},
+			sortTags([]string{
+				"1.0.0-alpha",
+				"1.0.0-alpha.1",
+				"1.0.0-alpha.beta",
+				"1.0.0-beta",
+				"1.0.0-beta.2",
+				"1.0.0-beta.11",
+				"1.0.0-rc.1",
+				"1.0.0",
+			}, ""),
+		)
+	})
+
+	t.Run("build metadata is ignored", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"1.1.0", "1.0.0+b", "1.0.0+a"},
+			sortTags([]string{"1.0.0+a", "1.1.0", "1.0.0+b"}, ""),
+		)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"app/1.10.0", "app/1.9.0", "app/foo"},
+			sortTags([]string{"app/1.9.0", "app/foo", "app/1.10.0"}, "app/"),
+		)
+	})
+
+	t.Run("invalid tags go last", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"v0.0.1", "pattern-1.2.3", "latest"},
+			sortTags([]string{"latest", "pattern-1.2.3", "v0.0.1"}, ""),
+		)
+	})
+
+	t.Run("invalid tags by name, descending", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"v1.0.0", "v-next", "nightly", "latest", "alpha"},
+			sortTags([]string{"latest", "alpha", "v1.0.0", "nightly", "v-next"}, "v"),
+		)
+	})
+}
+
+// specVersion is a randomly generated SemVer 2.0.0 version, used as input for
+// the property-based tests below.
+type specVersion string
+
+func (specVersion) Generate(r *rand.Rand, _ int) reflect.Value {
+	identifiers := []string{"alpha", "beta", "rc", "pre", "x-y", "0a"}
+	version := fmt.Sprintf("%d.%d.%d", r.Intn(3), r.Intn(3), r.Intn(3))
+	if r.Intn(2) == 0 {
+		var parts []string
+		for range 1 + r.Intn(3) {
+			if r.Intn(2) == 0 {
+				parts = append(parts, strconv.Itoa(r.Intn(12)))
+			} else {
+				parts = append(parts, identifiers[r.Intn(len(identifiers))])
+			}
+		}
+		version += "-" + strings.Join(parts, ".")
+	}
+	if r.Intn(4) == 0 {
+		version += "+build." + strconv.Itoa(r.Intn(3))
+	}
+	return reflect.ValueOf(specVersion(version))
+}
+
+// specCompare compares two versions following the precedence rules of
+// SemVer 2.0.0 (https://semver.org/#spec-item-11), independently of the
+// semver library used by sortTags.
+func specCompare(a, b string) int {
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aPre, aHasPre := strings.Cut(a, "-")
+	bCore, bPre, bHasPre := strings.Cut(b, "-")
+
+	aNums := strings.Split(aCore, ".")
+	bNums := strings.Split(bCore, ".")
+	for i := range aNums {
+		if c := compareNumeric(aNums[i], bNums[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case !aHasPre && !bHasPre:
+		return 0
+	case !aHasPre:
+		return 1
+	case !bHasPre:
+		return -1
+	}
+
+	aIDs := strings.Split(aPre, ".")
+	bIDs := strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		_, aErr := strconv.Atoi(aIDs[i])
+		_, bErr := strconv.Atoi(bIDs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = compareNumeric(aIDs[i], bIDs[i])
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(aIDs[i], bIDs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareNumeric(strconv.Itoa(len(aIDs)), strconv.Itoa(len(bIDs)))
+}
+
+func compareNumeric(a, b string) int {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func TestSortTagsFollowsSpec(t *testing.T) {
+	toTags := func(prefix string, versions []specVersion) []string {
+		tags := make([]string, 0, len(versions))
+		for _, v := range versions {
+			tags = append(tags, prefix+string(v))
+		}
+		return tags
+	}
+
+	t.Run("sorted in descending precedence", func(t *testing.T) {
+		property := func(versions []specVersion) bool {
+			sorted := sortTags(toTags("v", versions), "v")
+			for i := 1; i < len(sorted); i++ {
+				prev := strings.TrimPrefix(sorted[i-1], "v")
+				curr := strings.TrimPrefix(sorted[i], "v")
+				if specCompare(prev, curr) < 0 {
+					t.Logf("%q sorted before %q", sorted[i-1], sorted[i])
+					return false
+				}
+			}
+			return true
+		}
+		require.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("independent of input order", func(t *testing.T) {
+		property := func(versions []specVersion, seed int64) bool {
+			tags := toTags("app-", versions)
+			shuffled := append([]string(nil), tags...)
+			rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+			return reflect.DeepEqual(sortTags(tags, "app-"), sortTags(shuffled, "app-"))
+		}
+		require.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("keeps every tag", func(t *testing.T) {
+		property := func(versions []specVersion) bool {
+			return len(sortTags(toTags("", versions), "")) == len(versions)
+		}
+		require.NoError(t, quick.Check(property, nil))
+	})
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Tag is a tag with the commit it points to and who created it.
+type Tag struct {
+	Name string
+	// Commit is the hash of the commit the tag points to.
+	Commit string
+	// Annotated is true for annotated tags, false for lightweight ones.
+	Annotated bool
+	// Date is when annotated tags were created, and the commit date of
+	// lightweight ones.
+	Date time.Time
+	// Tagger is who created annotated tags, and the committer of lightweight
+	// ones.
+	Tagger Person
+}
+
+// tagFormat are the fields of tags, separated by NULs. The ones starting with
+// "*" are the fields of the commit annotated tags point to; for lightweight
+// tags, the commit fields are the ones without it.
+var tagFormat = strings.Join([]string{
+	"%(refname:strip=2)",
+	"%(objecttype)",
+	"%(objectname)",
+	"%(*objecttype)",
+	"%(*objectname)",
+	"%(creatordate:iso-strict)",
+	"%(taggername)",
+	"%(taggeremail)",
+	"%(committername)",
+	"%(committeremail)",
+}, "%00")
+
+// Tags returns the tags pointing to commits, sorted by descending SemVer
+// precedence once the prefix is removed, as the other functions listing tags.
+func Tags(ctx context.Context, prefix string) ([]Tag, error) {
+	out, err := run(ctx, "for-each-ref", "--format="+tagFormat, "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]Tag{}
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 10 {
+			continue
+		}
+		tag := Tag{Name: fields[0]}
+		date, _ := time.Parse(time.RFC3339, fields[5])
+		tag.Date = date
+		switch {
+		case fields[1] == "commit":
+			tag.Commit = fields[2]
+			tag.Tagger = Person{Name: fields[8], Email: trimEmail(fields[9])}
+		case fields[1] == "tag" && fields[3] == "commit":
+			tag.Commit = fields[4]
+			tag.Annotated = true
+			tag.Tagger = Person{Name: fields[6], Email: trimEmail(fields[7])}
+		default:
+			// Tags of trees and blobs, which are no versions.
+			continue
+		}
+		tags[tag.Name] = tag
+		names = append(names, tag.Name)
+	}
+
+	result := make([]Tag, 0, len(names))
+	for _, name := range sortTags(names, prefix) {
+		result = append(result, tags[name])
+	}
+	return result, nil
+}
+
+// trimEmail removes the angle brackets git puts around emails.
+func trimEmail(email string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(email, "<"), ">")
+}
+
+// HasTag reports whether the tag exists.
+func HasTag(ctx context.Context, tag string) (bool, error) {
+	_, err := run(ctx, "show-ref", "--verify", "--quiet", "refs/tags/"+tag)
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) && cmdErr.ExitCode == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateTag creates a lightweight tag on rev, which keeps the date of the
+// commit.
+func CreateTag(ctx context.Context, tag string, rev string) error {
+	_, err := run(ctx, "tag", tag, rev)
+	return err
+}
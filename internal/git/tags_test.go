@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	gitCommit(t, "chore: first")
+	gitTag(t, "v1.0.0")
+	gitCommit(t, "feat: second")
+	_, err := fakeGitRun("-c", "user.name=Tagger", "-c", "user.email=tagger@example.com", "tag", "-a", "v1.1.0", "-m", "release 1.1.0")
+	require.NoError(t, err)
+	gitTag(t, "v1.1.0-rc.1")
+	tree, err := run(context.Background(), "rev-parse", "HEAD^{tree}")
+	require.NoError(t, err)
+	_, err = fakeGitRun("tag", "a-tree", strings.TrimSpace(tree))
+	require.NoError(t, err)
+	head, err := run(context.Background(), "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	tags, err := Tags(context.Background(), "v")
+	require.NoError(t, err)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	require.Equal(t, []string{"v1.1.0", "v1.1.0-rc.1", "v1.0.0"}, names)
+
+	annotated := tags[0]
+	require.True(t, annotated.Annotated)
+	require.Equal(t, strings.TrimSpace(head), annotated.Commit)
+	require.Equal(t, Person{Name: "Tagger", Email: "tagger@example.com"}, annotated.Tagger)
+	require.WithinDuration(t, time.Now(), annotated.Date, time.Minute)
+
+	lightweight := tags[1]
+	require.False(t, lightweight.Annotated)
+	require.Equal(t, strings.TrimSpace(head), lightweight.Commit)
+	require.Equal(t, "svu", lightweight.Tagger.Name)
+	require.WithinDuration(t, time.Now(), lightweight.Date, time.Minute)
+}
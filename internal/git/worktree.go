@@ -0,0 +1,64 @@
+package git
+
+import (
+	"context"
+	"os"
+)
+
+// Worktree is a revision checked out in a temporary directory with git
+// worktree, for the tools which need the files of a past revision on disk.
+// It must be removed with Remove, or used through WithWorktree.
+type Worktree struct {
+	// Dir is the directory of the checkout.
+	Dir     string
+	removed bool
+}
+
+// AddWorktree checks rev out in a temporary directory, detached from any
+// branch.
+func AddWorktree(ctx context.Context, rev string) (*Worktree, error) {
+	dir, err := os.MkdirTemp("", "semtag-worktree-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := run(ctx, "worktree", "add", "--detach", "--quiet", dir, rev); err != nil {
+		_ = os.RemoveAll(dir)
+		// git may have registered the worktree before failing.
+		_, _ = run(context.WithoutCancel(ctx), "worktree", "prune")
+		return nil, err
+	}
+	return &Worktree{Dir: dir}, nil
+}
+
+// Remove deletes the checkout and unregisters it from the repository, even
+// when ctx is cancelled. It can be called several times.
+func (w *Worktree) Remove(ctx context.Context) error {
+	if w.removed {
+		return nil
+	}
+	w.removed = true
+
+	ctx = context.WithoutCancel(ctx)
+	if _, err := run(ctx, "worktree", "remove", "--force", w.Dir); err != nil {
+		// Remove what is left, and forget about it.
+		_ = os.RemoveAll(w.Dir)
+		_, _ = run(ctx, "worktree", "prune")
+		return err
+	}
+	return nil
+}
+
+// WithWorktree calls fn with the directory of rev checked out in a temporary
+// worktree, which is removed once fn returns or panics.
+func WithWorktree(ctx context.Context, rev string, fn func(dir string) error) (err error) {
+	w, err := AddWorktree(ctx, rev)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if removeErr := w.Remove(ctx); err == nil {
+			err = removeErr
+		}
+	}()
+	return fn(w.Dir)
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktree(t *testing.T) {
+	tempdir(t)
+	gitInit(t)
+	require.NoError(t, os.WriteFile("a.txt", []byte("first"), 0o644))
+	gitAdd(t, "a.txt")
+	gitCommit(t, "chore: first")
+	gitTag(t, "v1.0.0")
+	require.NoError(t, os.WriteFile("a.txt", []byte("second"), 0o644))
+	gitCommit(t, "chore: second")
+
+	t.Run("checkout", func(t *testing.T) {
+		var dir string
+		err := WithWorktree(context.Background(), "v1.0.0", func(d string) error {
+			dir = d
+			content, err := os.ReadFile(filepath.Join(d, "a.txt"))
+			require.NoError(t, err)
+			require.Equal(t, "first", string(content))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoDirExists(t, dir)
+		requireNoWorktrees(t)
+
+		// The checkout of the repository is left as it is.
+		content, err := os.ReadFile("a.txt")
+		require.NoError(t, err)
+		require.Equal(t, "second", string(content))
+	})
+
+	t.Run("removed on error", func(t *testing.T) {
+		failure := errors.New("boom")
+		var dir string
+		err := WithWorktree(context.Background(), "HEAD", func(d string) error {
+			dir = d
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		require.NoDirExists(t, dir)
+		requireNoWorktrees(t)
+	})
+
+	t.Run("removed after cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		w, err := AddWorktree(ctx, "HEAD")
+		require.NoError(t, err)
+		cancel()
+		require.NoError(t, w.Remove(ctx))
+		require.NoError(t, w.Remove(ctx))
+		require.NoDirExists(t, w.Dir)
+		requireNoWorktrees(t)
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		err := WithWorktree(context.Background(), "v9.9.9", func(string) error {
+			t.Fatal("unexpected call")
+			return nil
+		})
+		var cmdErr *CommandError
+		require.ErrorAs(t, err, &cmdErr)
+		requireNoWorktrees(t)
+	})
+}
+
+func requireNoWorktrees(tb testing.TB) {
+	tb.Helper()
+	out, err := run(context.Background(), "worktree", "list", "--porcelain")
+	require.NoError(tb, err)
+	require.Equal(tb, 1, strings.Count(out, "worktree "))
+}
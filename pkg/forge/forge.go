@@ -0,0 +1,223 @@
+// Package forge publishes releases on git forges (GitHub, GitLab and Gitea or
+// Forgejo) through their REST APIs.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Forge kinds, as accepted by New.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+// Kinds are the supported forges.
+var Kinds = []string{GitHub, GitLab, Gitea}
+
+// ErrUnknownForge is returned when the forge of a repository can't be
+// detected, or is not supported.
+var ErrUnknownForge = errors.New("unknown forge")
+
+// APIError is returned when the forge API answers with an error status.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the response body, usually holding the reason of the error.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	msg := strings.TrimSpace(e.Body)
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, msg)
+}
+
+// Release is a release to publish, for an existing tag.
+type Release struct {
+	Tag  string
+	Name string
+	// Body is the release notes, in markdown.
+	Body       string
+	Draft      bool
+	Prerelease bool
+	Assets     []Asset
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	// Name is the file name shown on the release, the base name of Path
+	// when empty.
+	Name string
+	Path string
+}
+
+func (a Asset) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
+
+// ReleasePublisher creates releases on a forge.
+type ReleasePublisher interface {
+	// Publish creates the release and uploads its assets, returning the web
+	// URL of the release.
+	Publish(ctx context.Context, release Release) (string, error)
+}
+
+// Config configures the API client of a forge.
+type Config struct {
+	// BaseURL is the URL of the API, e.g. https://api.github.com,
+	// https://gitlab.example.com/api/v4 or https://gitea.example.com/api/v1.
+	BaseURL string
+	// Repository is "owner/repo", or the full project path on GitLab.
+	Repository string
+	Token      string
+	// HTTPClient is used for requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// New returns the publisher of the given kind of forge.
+func New(kind string, cfg Config) (ReleasePublisher, error) {
+	switch kind {
+	case GitHub:
+		return NewGitHub(cfg), nil
+	case GitLab:
+		return NewGitLab(cfg), nil
+	case Gitea:
+		return NewGitea(cfg), nil
+	}
+	return nil, fmt.Errorf("%w '%s', expected one of %s", ErrUnknownForge, kind, strings.Join(Kinds, ", "))
+}
+
+// Detect guesses the kind of forge, the URL of its API and the repository from
+// the web URL of a repository, such as https://github.com/owner/repo. Self
+// hosted instances are recognised when their host name contains the name of
+// the forge, as in gitlab.example.com; otherwise ErrUnknownForge is returned.
+func Detect(repositoryURL string) (string, Config, error) {
+	u, err := url.Parse(repositoryURL)
+	if err != nil || u.Host == "" {
+		return "", Config{}, fmt.Errorf("%w: invalid repository URL '%s'", ErrUnknownForge, repositoryURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	origin := u.Scheme + "://" + u.Host
+	repository := strings.Trim(u.Path, "/")
+
+	switch {
+	case host == "github.com":
+		return GitHub, Config{BaseURL: "https://api.github.com", Repository: repository}, nil
+	case strings.Contains(host, "github"):
+		// GitHub Enterprise Server
+		return GitHub, Config{BaseURL: origin + "/api/v3", Repository: repository}, nil
+	case strings.Contains(host, "gitlab"):
+		return GitLab, Config{BaseURL: origin + "/api/v4", Repository: repository}, nil
+	case strings.Contains(host, "gitea"), strings.Contains(host, "forgejo"), host == "codeberg.org":
+		return Gitea, Config{BaseURL: origin + "/api/v1", Repository: repository}, nil
+	}
+	return "", Config{}, fmt.Errorf("%w: can't tell the forge of '%s'", ErrUnknownForge, repositoryURL)
+}
+
+// client holds what the forge implementations share.
+type client struct {
+	Config
+	// auth sets the authentication header of a request.
+	auth func(req *http.Request)
+}
+
+func newClient(cfg Config, auth func(req *http.Request)) client {
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return client{Config: cfg, auth: auth}
+}
+
+// do sends a request and decodes the JSON response into out, unless nil.
+func (c client) do(ctx context.Context, method, endpoint, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		c.auth(req)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{Method: method, URL: endpoint, StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, endpoint, err)
+	}
+	return nil
+}
+
+func (c client) doJSON(ctx context.Context, method, endpoint string, in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, endpoint, "application/json", bytes.NewReader(data), out)
+}
+
+// uploadFile sends a file as the body of the request. It is read in memory,
+// as some APIs require the Content-Length.
+func (c client) uploadFile(ctx context.Context, endpoint string, asset Asset, out any) error {
+	data, err := os.ReadFile(asset.Path)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, endpoint, "application/octet-stream", bytes.NewReader(data), out)
+}
+
+// uploadForm sends a file as a multipart/form-data field.
+func (c client) uploadForm(ctx context.Context, endpoint, field string, asset Asset, out any) error {
+	f, err := os.Open(asset.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile(field, asset.name())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, endpoint, w.FormDataContentType(), &body, out)
+}
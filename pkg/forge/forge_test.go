@@ -0,0 +1,224 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// request is a request received by the stand-in server.
+type request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   string
+	// File is the content of the uploaded form file, if any.
+	File string
+}
+
+// server is a stand-in forge API answering requests to "METHOD path" with
+// the given JSON, in which $SERVER is replaced with its URL. It records the
+// requests it receives.
+func server(tb testing.TB, responses map[string]string) (*httptest.Server, *[]request) {
+	tb.Helper()
+	var requests []request
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery, Header: r.Header}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			require.NoError(tb, r.ParseMultipartForm(1<<20))
+			for _, files := range r.MultipartForm.File {
+				f, err := files[0].Open()
+				require.NoError(tb, err)
+				data, _ := io.ReadAll(f)
+				req.File = files[0].Filename + ": " + string(data)
+			}
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			req.Body = string(data)
+		}
+		requests = append(requests, req)
+
+		response, ok := responses[r.Method+" "+req.Path]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, strings.ReplaceAll(response, "$SERVER", srv.URL))
+	}))
+	tb.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func asset(tb testing.TB) Asset {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "semtag_linux_amd64.tar.gz")
+	require.NoError(tb, os.WriteFile(path, []byte("binary"), 0o644))
+	return Asset{Path: path}
+}
+
+func decode(tb testing.TB, body string) map[string]any {
+	tb.Helper()
+	var v map[string]any
+	require.NoError(tb, json.Unmarshal([]byte(body), &v))
+	return v
+}
+
+func TestGitHub(t *testing.T) {
+	srv, requests := server(t, map[string]string{
+		"POST /repos/foo/bar/releases":          `{"id":1,"html_url":"https://github.com/foo/bar/releases/tag/v1.2.3-rc.1","upload_url":"$SERVER/repos/foo/bar/releases/1/assets{?name,label}"}`,
+		"POST /repos/foo/bar/releases/1/assets": `{}`,
+	})
+
+	publisher := NewGitHub(Config{BaseURL: srv.URL, Repository: "foo/bar", Token: "secret"})
+	url, err := publisher.Publish(context.Background(), Release{
+		Tag:        "v1.2.3-rc.1",
+		Name:       "v1.2.3-rc.1",
+		Body:       "notes",
+		Prerelease: true,
+		Assets:     []Asset{asset(t)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/foo/bar/releases/tag/v1.2.3-rc.1", url)
+
+	require.Len(t, *requests, 2)
+	create := (*requests)[0]
+	require.Equal(t, "Bearer secret", create.Header.Get("Authorization"))
+	require.Equal(t, map[string]any{
+		"tag_name":   "v1.2.3-rc.1",
+		"name":       "v1.2.3-rc.1",
+		"body":       "notes",
+		"draft":      false,
+		"prerelease": true,
+	}, decode(t, create.Body))
+
+	upload := (*requests)[1]
+	require.Equal(t, "name=semtag_linux_amd64.tar.gz", upload.Query)
+	require.Equal(t, "application/octet-stream", upload.Header.Get("Content-Type"))
+	require.Equal(t, "binary", upload.Body)
+}
+
+func TestGitLab(t *testing.T) {
+	srv, requests := server(t, map[string]string{
+		"POST /api/v4/projects/foo%2Fbar/uploads":  `{"url":"/uploads/abc/semtag_linux_amd64.tar.gz","full_path":"/-/project/1/uploads/abc/semtag_linux_amd64.tar.gz"}`,
+		"POST /api/v4/projects/foo%2Fbar/releases": `{"_links":{"self":"https://gitlab.com/foo/bar/-/releases/v1.2.3"}}`,
+	})
+
+	publisher := NewGitLab(Config{BaseURL: srv.URL + "/api/v4", Repository: "foo/bar", Token: "secret"})
+	url, err := publisher.Publish(context.Background(), Release{
+		Tag:    "v1.2.3",
+		Name:   "v1.2.3",
+		Body:   "notes",
+		Assets: []Asset{asset(t)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://gitlab.com/foo/bar/-/releases/v1.2.3", url)
+
+	require.Len(t, *requests, 2)
+	upload := (*requests)[0]
+	require.Equal(t, "secret", upload.Header.Get("PRIVATE-TOKEN"))
+	require.Equal(t, "semtag_linux_amd64.tar.gz: binary", upload.File)
+
+	require.Equal(t, map[string]any{
+		"tag_name":    "v1.2.3",
+		"name":        "v1.2.3",
+		"description": "notes",
+		"assets": map[string]any{
+			"links": []any{map[string]any{
+				"name":      "semtag_linux_amd64.tar.gz",
+				"url":       srv.URL + "/-/project/1/uploads/abc/semtag_linux_amd64.tar.gz",
+				"link_type": "package",
+			}},
+		},
+	}, decode(t, (*requests)[1].Body))
+
+	t.Run("draft", func(t *testing.T) {
+		_, err := publisher.Publish(context.Background(), Release{Tag: "v1.2.3", Draft: true})
+		require.Error(t, err)
+	})
+}
+
+func TestGitea(t *testing.T) {
+	srv, requests := server(t, map[string]string{
+		"POST /api/v1/repos/foo/bar/releases":          `{"id":7,"html_url":"https://codeberg.org/foo/bar/releases/tag/v1.2.3"}`,
+		"POST /api/v1/repos/foo/bar/releases/7/assets": `{}`,
+	})
+
+	publisher := NewGitea(Config{BaseURL: srv.URL + "/api/v1", Repository: "foo/bar", Token: "secret"})
+	url, err := publisher.Publish(context.Background(), Release{
+		Tag:    "v1.2.3",
+		Draft:  true,
+		Assets: []Asset{asset(t)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://codeberg.org/foo/bar/releases/tag/v1.2.3", url)
+
+	require.Len(t, *requests, 2)
+	create := (*requests)[0]
+	require.Equal(t, "token secret", create.Header.Get("Authorization"))
+	require.Equal(t, map[string]any{
+		"tag_name":   "v1.2.3",
+		"draft":      true,
+		"prerelease": false,
+	}, decode(t, create.Body))
+
+	upload := (*requests)[1]
+	require.Equal(t, "name=semtag_linux_amd64.tar.gz", upload.Query)
+	require.Equal(t, "semtag_linux_amd64.tar.gz: binary", upload.File)
+}
+
+func TestAPIError(t *testing.T) {
+	srv, _ := server(t, nil)
+
+	_, err := NewGitHub(Config{BaseURL: srv.URL, Repository: "foo/bar"}).Publish(context.Background(), Release{Tag: "v1.2.3"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, srv.URL+"/repos/foo/bar/releases", apiErr.URL)
+	require.Contains(t, err.Error(), "Not Found")
+}
+
+func TestNew(t *testing.T) {
+	for _, kind := range Kinds {
+		publisher, err := New(kind, Config{})
+		require.NoError(t, err)
+		require.NotNil(t, publisher)
+	}
+
+	_, err := New("sourcehut", Config{})
+	require.ErrorIs(t, err, ErrUnknownForge)
+}
+
+func TestDetect(t *testing.T) {
+	for repositoryURL, expected := range map[string]struct {
+		kind string
+		cfg  Config
+	}{
+		"https://github.com/foo/bar":             {GitHub, Config{BaseURL: "https://api.github.com", Repository: "foo/bar"}},
+		"https://github.example.com/foo/bar":     {GitHub, Config{BaseURL: "https://github.example.com/api/v3", Repository: "foo/bar"}},
+		"https://gitlab.com/group/sub/bar":       {GitLab, Config{BaseURL: "https://gitlab.com/api/v4", Repository: "group/sub/bar"}},
+		"https://codeberg.org/foo/bar":           {Gitea, Config{BaseURL: "https://codeberg.org/api/v1", Repository: "foo/bar"}},
+		"https://gitea.example.com:3000/foo/bar": {Gitea, Config{BaseURL: "https://gitea.example.com:3000/api/v1", Repository: "foo/bar"}},
+	} {
+		t.Run(repositoryURL, func(t *testing.T) {
+			kind, cfg, err := Detect(repositoryURL)
+			require.NoError(t, err)
+			require.Equal(t, expected.kind, kind)
+			require.Equal(t, expected.cfg, cfg)
+		})
+	}
+
+	_, _, err := Detect("https://example.com/foo/bar")
+	require.ErrorIs(t, err, ErrUnknownForge)
+}
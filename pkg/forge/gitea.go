@@ -0,0 +1,63 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GiteaPublisher publishes releases through the Gitea REST API, which Forgejo
+// also implements.
+type GiteaPublisher struct {
+	client
+}
+
+// NewGitea returns a publisher for a Gitea or Forgejo instance. There is no
+// default cfg.BaseURL, it must be set to the API of the instance, e.g.
+// https://codeberg.org/api/v1.
+func NewGitea(cfg Config) *GiteaPublisher {
+	return &GiteaPublisher{newClient(cfg, func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+cfg.Token)
+	})}
+}
+
+type giteaRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+type giteaCreated struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Publish creates the release, then attaches its assets.
+func (p *GiteaPublisher) Publish(ctx context.Context, release Release) (string, error) {
+	if p.BaseURL == "" {
+		return "", fmt.Errorf("%w: the API URL of gitea instances must be set", ErrUnknownForge)
+	}
+
+	releases := p.BaseURL + "/repos/" + p.Repository + "/releases"
+	var created giteaCreated
+	if err := p.doJSON(ctx, http.MethodPost, releases, giteaRelease{
+		TagName:    release.Tag,
+		Name:       release.Name,
+		Body:       release.Body,
+		Draft:      release.Draft,
+		Prerelease: release.Prerelease,
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create release: %w", err)
+	}
+
+	for _, asset := range release.Assets {
+		endpoint := fmt.Sprintf("%s/%d/assets?name=%s", releases, created.ID, url.QueryEscape(asset.name()))
+		if err := p.uploadForm(ctx, endpoint, "attachment", asset, nil); err != nil {
+			return created.HTMLURL, fmt.Errorf("failed to upload %s: %w", asset.name(), err)
+		}
+	}
+	return created.HTMLURL, nil
+}
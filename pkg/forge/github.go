@@ -0,0 +1,62 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHubPublisher publishes releases through the GitHub REST API.
+type GitHubPublisher struct {
+	client
+}
+
+// NewGitHub returns a publisher for GitHub, or GitHub Enterprise Server when
+// cfg.BaseURL is set to its API, e.g. https://github.example.com/api/v3.
+func NewGitHub(cfg Config) *GitHubPublisher {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.github.com"
+	}
+	return &GitHubPublisher{newClient(cfg, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	})}
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+type githubCreated struct {
+	HTMLURL   string `json:"html_url"`
+	UploadURL string `json:"upload_url"`
+}
+
+// Publish creates the release, then uploads its assets.
+func (p *GitHubPublisher) Publish(ctx context.Context, release Release) (string, error) {
+	var created githubCreated
+	if err := p.doJSON(ctx, http.MethodPost, p.BaseURL+"/repos/"+p.Repository+"/releases", githubRelease{
+		TagName:    release.Tag,
+		Name:       release.Name,
+		Body:       release.Body,
+		Draft:      release.Draft,
+		Prerelease: release.Prerelease,
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create release: %w", err)
+	}
+
+	// upload_url is a URI template, e.g. ".../assets{?name,label}".
+	uploadURL, _, _ := strings.Cut(created.UploadURL, "{")
+	for _, asset := range release.Assets {
+		endpoint := uploadURL + "?name=" + url.QueryEscape(asset.name())
+		if err := p.uploadFile(ctx, endpoint, asset, nil); err != nil {
+			return created.HTMLURL, fmt.Errorf("failed to upload %s: %w", asset.name(), err)
+		}
+	}
+	return created.HTMLURL, nil
+}
@@ -0,0 +1,92 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLabPublisher publishes releases through the GitLab REST API. GitLab has
+// no draft releases, and no pre-release flag: Release.Prerelease is ignored.
+type GitLabPublisher struct {
+	client
+}
+
+// NewGitLab returns a publisher for gitlab.com, or a self-hosted instance when
+// cfg.BaseURL is set to its API, e.g. https://gitlab.example.com/api/v4.
+func NewGitLab(cfg Config) *GitLabPublisher {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://gitlab.com/api/v4"
+	}
+	return &GitLabPublisher{newClient(cfg, func(req *http.Request) {
+		req.Header.Set("PRIVATE-TOKEN", cfg.Token)
+	})}
+}
+
+type gitlabRelease struct {
+	TagName     string       `json:"tag_name"`
+	Name        string       `json:"name,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Assets      gitlabAssets `json:"assets"`
+}
+
+type gitlabAssets struct {
+	Links []gitlabLink `json:"links"`
+}
+
+type gitlabLink struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	LinkType string `json:"link_type"`
+}
+
+type gitlabUpload struct {
+	FullPath string `json:"full_path"`
+}
+
+type gitlabCreated struct {
+	Links struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+// Publish uploads the assets to the project, then creates the release linking
+// to them.
+func (p *GitLabPublisher) Publish(ctx context.Context, release Release) (string, error) {
+	if release.Draft {
+		return "", errors.New("gitlab does not support draft releases")
+	}
+
+	project := p.BaseURL + "/projects/" + url.PathEscape(p.Repository)
+	links := []gitlabLink{}
+	for _, asset := range release.Assets {
+		var uploaded gitlabUpload
+		if err := p.uploadForm(ctx, project+"/uploads", "file", asset, &uploaded); err != nil {
+			return "", fmt.Errorf("failed to upload %s: %w", asset.name(), err)
+		}
+		links = append(links, gitlabLink{
+			Name:     asset.name(),
+			URL:      p.webURL() + uploaded.FullPath,
+			LinkType: "package",
+		})
+	}
+
+	var created gitlabCreated
+	if err := p.doJSON(ctx, http.MethodPost, project+"/releases", gitlabRelease{
+		TagName:     release.Tag,
+		Name:        release.Name,
+		Description: release.Body,
+		Assets:      gitlabAssets{Links: links},
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create release: %w", err)
+	}
+	return created.Links.Self, nil
+}
+
+// webURL is the URL of the instance, which uploads are relative to.
+func (p *GitLabPublisher) webURL() string {
+	return strings.TrimSuffix(p.BaseURL, "/api/v4")
+}
@@ -0,0 +1,469 @@
+package semtag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// APIVerification decides what Next does when the exported Go API changed
+// more than the commits tell.
+type APIVerification string
+
+const (
+	// APIVerificationOff doesn't compare the API. This is the default.
+	APIVerificationOff APIVerification = ""
+	// APIVerificationWarn compares the API and sets Result.API, leaving it to
+	// the caller to warn when Result.APIBumpTooLow reports true.
+	APIVerificationWarn APIVerification = "warn"
+	// APIVerificationFail fails with an APIBumpError when the commits warrant
+	// a lower bump than the API changes require.
+	APIVerificationFail APIVerification = "fail"
+)
+
+// APIChangeKind is how an exported symbol changed.
+type APIChangeKind string
+
+const (
+	APIRemoved APIChangeKind = "removed"
+	APIChanged APIChangeKind = "changed"
+	APIAdded   APIChangeKind = "added"
+)
+
+// APIChange is a change of an exported symbol of a Go package.
+type APIChange struct {
+	// Symbol is the directory of the package, relative to the root of the
+	// repository, and the name of the symbol, e.g. "pkg/semtag.Options.Ref".
+	Symbol string
+	Kind   APIChangeKind
+	// Before and After describe the declaration of the symbol, empty when it
+	// doesn't exist.
+	Before string
+	After  string
+	// Bump is the bump the change requires: BumpMajor for breaking changes,
+	// BumpMinor for additions.
+	Bump Bump
+}
+
+func (c APIChange) String() string {
+	switch c.Kind {
+	case APIRemoved:
+		return fmt.Sprintf("%s removed", c.Symbol)
+	case APIAdded:
+		return fmt.Sprintf("%s added", c.Symbol)
+	}
+	return fmt.Sprintf("%s changed from %s to %s", c.Symbol, c.Before, c.After)
+}
+
+// APIDiff is the difference between the exported Go API of two revisions.
+type APIDiff struct {
+	From string
+	To   string
+	// Changes are sorted by symbol.
+	Changes []APIChange
+	// Bump is the highest bump the changes require, BumpNone when the API
+	// didn't change.
+	Bump Bump
+	// TypeErrors are the errors found type-checking the packages, like
+	// imports which couldn't be resolved. The symbols they affect have the
+	// same invalid type at both revisions, so their changes are missed: the
+	// comparison is incomplete when there are any.
+	TypeErrors []string
+}
+
+// APIBumpError is returned by Next with APIVerificationFail when the commits
+// warrant a lower bump than the changes of the exported Go API require.
+type APIBumpError struct {
+	// Bump is the bump warranted by the commits, and Required the one
+	// required by Changes.
+	Bump     Bump
+	Required Bump
+	Changes  []APIChange
+}
+
+func (e *APIBumpError) Error() string {
+	changes := make([]string, 0, len(e.Changes))
+	for _, change := range e.Changes {
+		changes = append(changes, change.String())
+	}
+	return fmt.Sprintf("the API changes require a %s bump, but the commits warrant %s: %s", e.Required, e.Bump, strings.Join(changes, "; "))
+}
+
+// DiffAPI compares the exported API of the Go packages of two revisions. The
+// packages are the ones in opts.Paths, or below the current directory, left
+// aside commands, internal packages and tests. Both revisions are checked out
+// and type-checked, and the types of the symbols compared: renaming parameters
+// changes nothing, while changing a type, even through an alias or the
+// inferred type of a variable, removing a symbol or adding a method to an
+// existing interface is breaking, and adding a symbol is additive.
+func DiffAPI(ctx context.Context, opts Options, from, to string) (*APIDiff, error) {
+	if err := git.EnsureRepo(ctx); err != nil {
+		return nil, err
+	}
+	before, beforeErrors, err := readAPI(ctx, from, opts.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the API of %s: %w", from, err)
+	}
+	after, afterErrors, err := readAPI(ctx, to, opts.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the API of %s: %w", to, err)
+	}
+
+	diff := &APIDiff{From: from, To: to, TypeErrors: append(beforeErrors, afterErrors...)}
+	for symbol, b := range before {
+		a, ok := after[symbol]
+		switch {
+		case !ok:
+			diff.Changes = append(diff.Changes, APIChange{Symbol: symbol, Kind: APIRemoved, Before: b.decl, Bump: BumpMajor})
+		case a.decl != b.decl:
+			diff.Changes = append(diff.Changes, APIChange{Symbol: symbol, Kind: APIChanged, Before: b.decl, After: a.decl, Bump: BumpMajor})
+		}
+	}
+	for symbol, a := range after {
+		if _, ok := before[symbol]; ok {
+			continue
+		}
+		bump := BumpMinor
+		if _, ok := before[a.iface]; ok {
+			bump = BumpMajor
+		}
+		diff.Changes = append(diff.Changes, APIChange{Symbol: symbol, Kind: APIAdded, After: a.decl, Bump: bump})
+	}
+
+	slices.SortFunc(diff.Changes, func(a, b APIChange) int {
+		return strings.Compare(a.Symbol, b.Symbol)
+	})
+	for _, change := range diff.Changes {
+		diff.Bump = max(diff.Bump, change.Bump)
+	}
+	return diff, nil
+}
+
+// verifyAPI compares the API of the previous stable tag with the one of
+// opts.Ref, failing with APIVerificationFail when bump is too low.
+func verifyAPI(ctx context.Context, opts Options, previousTag string, bump Bump) (*APIDiff, error) {
+	diff, err := DiffAPI(ctx, opts, "tags/"+previousTag, opts.ref())
+	if err != nil {
+		return nil, err
+	}
+	if opts.VerifyAPI == APIVerificationFail && bump < diff.Bump {
+		var changes []APIChange
+		for _, change := range diff.Changes {
+			if change.Bump > bump {
+				changes = append(changes, change)
+			}
+		}
+		return nil, &APIBumpError{Bump: bump, Required: diff.Bump, Changes: changes}
+	}
+	return diff, nil
+}
+
+// apiSymbol is an exported symbol of a package.
+type apiSymbol struct {
+	// decl describes the declaration; a change of it is breaking.
+	decl string
+	// iface is set for the methods of interfaces to the symbol of the
+	// interface, whose implementations outside of the package break when a
+	// method is added to it.
+	iface string
+}
+
+// readAPI returns the exported symbols of the Go packages of rev, which it
+// type-checks in a temporary worktree, and the type errors found doing so.
+func readAPI(ctx context.Context, rev string, paths []string) (map[string]apiSymbol, []string, error) {
+	files, err := git.ListFiles(ctx, rev, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	var dirs []string
+	for _, file := range files {
+		if dir := path.Dir(file); isAPIFile(file) && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	api := map[string]apiSymbol{}
+	var typeErrors []string
+	err = git.WithWorktree(ctx, rev, func(root string) error {
+		imports := newSourceImporter(root)
+		for _, dir := range dirs {
+			if err := addPackage(api, imports, dir); err != nil {
+				return err
+			}
+		}
+		for _, err := range imports.errors {
+			typeErrors = append(typeErrors, rev+": "+err)
+		}
+		return nil
+	})
+	return api, typeErrors, err
+}
+
+// addPackage type-checks the package of dir and adds its exported symbols to
+// api. Type errors, like imports which can't be found, are recorded by
+// imports rather than failing.
+func addPackage(api map[string]apiSymbol, imports *sourceImporter, dir string) error {
+	bp, err := imports.ctxt.ImportDir(filepath.Join(imports.ctxt.Dir, filepath.FromSlash(dir)), 0)
+	var noGo *build.NoGoError
+	if errors.As(err, &noGo) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bp.Name == "main" {
+		return nil
+	}
+
+	pkg, err := imports.check(dir, bp)
+	if err != nil {
+		return err
+	}
+
+	prefix := dir
+	if dir == "." {
+		prefix = bp.Name
+	}
+	// Types of the package are unqualified, others have their import path.
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Path()
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		symbol := prefix + "." + name
+		switch obj := obj.(type) {
+		case *types.Func:
+			api[symbol] = apiSymbol{decl: "func" + signature(obj.Type().(*types.Signature), qualifier)}
+		case *types.Var:
+			api[symbol] = apiSymbol{decl: "var " + types.TypeString(obj.Type(), qualifier)}
+		case *types.Const:
+			api[symbol] = apiSymbol{decl: "const " + types.TypeString(obj.Type(), qualifier)}
+		case *types.TypeName:
+			addType(api, symbol, obj, qualifier)
+		}
+	}
+	return nil
+}
+
+// addType adds an exported type, with its exported fields and methods. The
+// members of an alias are the ones of the type it stands for, so that changes
+// made to it through the alias are found too.
+func addType(api map[string]apiSymbol, symbol string, obj *types.TypeName, q types.Qualifier) {
+	typ := types.Unalias(obj.Type())
+	if obj.IsAlias() {
+		api[symbol] = apiSymbol{decl: "type = " + types.TypeString(typ, q)}
+	} else {
+		params := ""
+		if named, ok := typ.(*types.Named); ok {
+			params = typeParams(named.TypeParams(), q)
+		}
+		switch t := typ.Underlying().(type) {
+		case *types.Struct:
+			api[symbol] = apiSymbol{decl: "type" + params + " struct"}
+		case *types.Interface:
+			if t.IsMethodSet() {
+				api[symbol] = apiSymbol{decl: "type" + params + " interface"}
+			} else {
+				// Type constraints.
+				api[symbol] = apiSymbol{decl: "type" + params + " " + types.TypeString(t, q)}
+			}
+		case *types.Signature:
+			api[symbol] = apiSymbol{decl: "type" + params + " func" + signature(t, q)}
+		default:
+			api[symbol] = apiSymbol{decl: "type" + params + " " + types.TypeString(t, q)}
+		}
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *types.Struct:
+		for i := range t.NumFields() {
+			field := t.Field(i)
+			if !field.Exported() {
+				continue
+			}
+			kind := "field "
+			if field.Embedded() {
+				kind = "embedded "
+			}
+			api[symbol+"."+field.Name()] = apiSymbol{decl: kind + types.TypeString(field.Type(), q)}
+		}
+	case *types.Interface:
+		for i := range t.NumMethods() {
+			if method := t.Method(i); method.Exported() {
+				api[symbol+"."+method.Name()] = apiSymbol{decl: "method" + signature(method.Type().(*types.Signature), q), iface: symbol}
+			}
+		}
+		return
+	case *types.Pointer:
+		return
+	}
+
+	// The methods, including the promoted ones, and whether they need a
+	// pointer receiver.
+	if _, ok := typ.(*types.Named); !ok {
+		return
+	}
+	values := types.NewMethodSet(typ)
+	methods := types.NewMethodSet(types.NewPointer(typ))
+	for i := range methods.Len() {
+		method := methods.At(i).Obj()
+		if !method.Exported() {
+			continue
+		}
+		recv := "*" + obj.Name()
+		if values.Lookup(method.Pkg(), method.Name()) != nil {
+			recv = obj.Name()
+		}
+		sig := method.Type().(*types.Signature)
+		api[symbol+"."+method.Name()] = apiSymbol{decl: "func (" + recv + ")" + signature(sig, q)}
+	}
+}
+
+// signature describes the type parameters, parameters and results of a
+// function, without their names.
+func signature(sig *types.Signature, q types.Qualifier) string {
+	s := typeParams(sig.TypeParams(), q) + "(" + tupleTypes(sig.Params(), sig.Variadic(), q) + ")"
+	if sig.Results().Len() > 0 {
+		s += " (" + tupleTypes(sig.Results(), false, q) + ")"
+	}
+	return s
+}
+
+func typeParams(list *types.TypeParamList, q types.Qualifier) string {
+	if list.Len() == 0 {
+		return ""
+	}
+	constraints := make([]string, 0, list.Len())
+	for i := range list.Len() {
+		constraints = append(constraints, types.TypeString(list.At(i).Constraint(), q))
+	}
+	return "[" + strings.Join(constraints, ", ") + "]"
+}
+
+// tupleTypes lists the types of parameters or results.
+func tupleTypes(tuple *types.Tuple, variadic bool, q types.Qualifier) string {
+	names := make([]string, 0, tuple.Len())
+	for i := range tuple.Len() {
+		typ := tuple.At(i).Type()
+		if slice, ok := typ.(*types.Slice); ok && variadic && i == tuple.Len()-1 {
+			names = append(names, "..."+types.TypeString(slice.Elem(), q))
+			continue
+		}
+		names = append(names, types.TypeString(typ, q))
+	}
+	return strings.Join(names, ", ")
+}
+
+// sourceImporter type-checks the packages imported by the ones of the API
+// from their source, resolving them from a worktree rather than the current
+// directory. Like the packages of the API, they are checked without their
+// function bodies, and without cgo.
+type sourceImporter struct {
+	ctxt build.Context
+	fset *token.FileSet
+	// packages are the imported packages by import path, nil while they
+	// are being checked.
+	packages map[string]*types.Package
+	// errors are the type errors found, with the files of the worktree
+	// relative to its root.
+	errors []string
+}
+
+func newSourceImporter(root string) *sourceImporter {
+	ctxt := build.Default
+	ctxt.Dir, ctxt.CgoEnabled = root, false
+	return &sourceImporter{ctxt: ctxt, fset: token.NewFileSet(), packages: map[string]*types.Package{}}
+}
+
+func (s *sourceImporter) Import(path string) (*types.Package, error) {
+	return s.ImportFrom(path, s.ctxt.Dir, 0)
+}
+
+func (s *sourceImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := s.ctxt.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := s.packages[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	s.packages[bp.ImportPath] = nil
+	pkg, err := s.check(bp.ImportPath, bp)
+	if err != nil {
+		delete(s.packages, bp.ImportPath)
+		return nil, err
+	}
+	s.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// check type-checks a package, recording the type errors rather than failing.
+func (s *sourceImporter) check(path string, bp *build.Package) (*types.Package, error) {
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(s.fset, filepath.Join(bp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: s, IgnoreFuncBodies: true, Error: s.addError}
+	pkg, _ := conf.Check(path, s.fset, files, nil)
+	return pkg, nil
+}
+
+func (s *sourceImporter) addError(err error) {
+	var typeErr types.Error
+	if !errors.As(err, &typeErr) {
+		s.errors = append(s.errors, err.Error())
+		return
+	}
+	pos := typeErr.Fset.Position(typeErr.Pos)
+	if rel, err := filepath.Rel(s.ctxt.Dir, pos.Filename); err == nil && filepath.IsLocal(rel) {
+		pos.Filename = filepath.ToSlash(rel)
+	}
+	s.errors = append(s.errors, fmt.Sprintf("%s: %s", pos, typeErr.Msg))
+}
+
+// isAPIFile reports whether a file is part of the exported API: Go files
+// other than tests, outside of internal, testdata and vendor directories.
+func isAPIFile(file string) bool {
+	if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+		return false
+	}
+	dir := path.Dir(file)
+	if dir == "." {
+		return true
+	}
+	for _, dir := range strings.Split(dir, "/") {
+		if dir == "internal" || dir == "testdata" || dir == "vendor" ||
+			strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_") {
+			return false
+		}
+	}
+	return true
+}
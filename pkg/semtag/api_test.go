@@ -0,0 +1,215 @@
+package semtag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const apiBefore = `package lib
+
+type Client struct {
+	URL     string
+	Timeout int
+	retries int
+}
+
+func (c *Client) Do(path string, body []byte) error { return nil }
+
+type Store interface {
+	Get(key string) (string, error)
+}
+
+const Version = "1"
+
+func New(url string) *Client { return nil }
+
+func helper() {}
+`
+
+func TestDiffAPI(t *testing.T) {
+	setup := func(tb testing.TB, after string) {
+		tb.Helper()
+		tempRepo(tb)
+		writeFile(tb, "lib/lib.go", apiBefore)
+		writeFile(tb, "lib/lib_test.go", "package lib\n\nfunc TestMe() {}\n")
+		writeFile(tb, "internal/x/x.go", "package x\n\nfunc Hidden() {}\n")
+		writeFile(tb, "cmd/tool/main.go", "package main\n\nfunc Main() {}\n")
+		gitRun(tb, "add", ".")
+		gitCommit(tb, "feat: lib")
+		gitTag(tb, "v1.0.0")
+
+		writeFile(tb, "lib/lib.go", after)
+		writeFile(tb, "internal/x/x.go", "package x\n")
+		writeFile(tb, "cmd/tool/main.go", "package main\n")
+		writeFile(tb, "lib/lib_test.go", "package lib\n")
+		gitRun(tb, "add", ".")
+		gitCommit(tb, "fix: lib")
+	}
+	diff := func(tb testing.TB) *APIDiff {
+		tb.Helper()
+		diff, err := DiffAPI(context.Background(), Options{}, "v1.0.0", "HEAD")
+		require.NoError(tb, err)
+		return diff
+	}
+
+	t.Run("none", func(t *testing.T) {
+		// Renamed parameters, unexported symbols, tests, commands and
+		// internal packages don't count.
+		setup(t, `package lib
+
+type Client struct {
+	URL, timeoutless string
+	Timeout          int
+}
+
+func (c *Client) Do(p string, b []byte) error { return nil }
+
+type Store interface {
+	Get(k string) (string, error)
+}
+
+const Version = "2"
+
+func New(u string) *Client { return nil }
+`)
+		d := diff(t)
+		require.Empty(t, d.Changes)
+		require.Equal(t, BumpNone, d.Bump)
+	})
+
+	t.Run("additive", func(t *testing.T) {
+		setup(t, apiBefore+`
+func (c *Client) Close() error { return nil }
+
+type Option func(c *Client)
+
+type Reader interface {
+	Read() ([]byte, error)
+}
+`)
+		// The methods of a new interface don't break anything.
+		d := diff(t)
+		require.Equal(t, BumpMinor, d.Bump)
+		require.Equal(t, []APIChange{
+			{Symbol: "lib.Client.Close", Kind: APIAdded, After: "func (*Client)() (error)", Bump: BumpMinor},
+			{Symbol: "lib.Option", Kind: APIAdded, After: "type func(*Client)", Bump: BumpMinor},
+			{Symbol: "lib.Reader", Kind: APIAdded, After: "type interface", Bump: BumpMinor},
+			{Symbol: "lib.Reader.Read", Kind: APIAdded, After: "method() ([]byte, error)", Bump: BumpMinor},
+		}, d.Changes)
+	})
+
+	t.Run("breaking", func(t *testing.T) {
+		setup(t, `package lib
+
+type Client struct {
+	URL     string
+	Timeout int64
+}
+
+func (c Client) Do(path string, body []byte) error { return nil }
+
+type Store interface {
+	Get(key string) (string, error)
+	Put(key, value string) error
+}
+
+const Version = "1"
+`)
+		d := diff(t)
+		require.Equal(t, BumpMajor, d.Bump)
+		var changes []string
+		for _, change := range d.Changes {
+			changes = append(changes, change.String())
+		}
+		require.Equal(t, []string{
+			"lib.Client.Do changed from func (*Client)(string, []byte) (error) to func (Client)(string, []byte) (error)",
+			"lib.Client.Timeout changed from field int to field int64",
+			"lib.New removed",
+			"lib.Store.Put added",
+		}, changes)
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		// Inferred types, and types changed through an alias of an internal
+		// package.
+		tempRepo(t)
+		writeFile(t, "go.mod", "module example.com/m\n\ngo 1.21\n")
+		writeFile(t, "internal/x/x.go", "package x\n\ntype Config struct{ Name string }\n")
+		writeFile(t, "lib.go", "package lib\n\nimport \"example.com/m/internal/x\"\n\ntype Config = x.Config\n\nvar Limit = 1\n")
+		gitRun(t, "add", ".")
+		gitCommit(t, "feat: lib")
+		gitTag(t, "v1.0.0")
+		writeFile(t, "internal/x/x.go", "package x\n\ntype Config struct{ Name []string }\n")
+		writeFile(t, "lib.go", "package lib\n\nimport \"example.com/m/internal/x\"\n\ntype Config = x.Config\n\nvar Limit = int64(1)\n")
+		gitRun(t, "add", ".")
+		gitCommit(t, "fix: lib")
+
+		d := diff(t)
+		require.Equal(t, BumpMajor, d.Bump)
+		require.Equal(t, []APIChange{
+			{Symbol: "lib.Config.Name", Kind: APIChanged, Before: "field string", After: "field []string", Bump: BumpMajor},
+			{Symbol: "lib.Limit", Kind: APIChanged, Before: "var int", After: "var int64", Bump: BumpMajor},
+		}, d.Changes)
+		require.Empty(t, d.TypeErrors)
+	})
+
+	t.Run("type errors", func(t *testing.T) {
+		// The changes of symbols whose types can't be resolved are missed,
+		// but the comparison tells it is incomplete.
+		tempRepo(t)
+		writeFile(t, "go.mod", "module example.com/m\n\ngo 1.21\n")
+		writeFile(t, "lib.go", "package lib\n\nimport \"example.com/missing\"\n\nvar Client missing.Client\n")
+		gitRun(t, "add", ".")
+		gitCommit(t, "feat: lib")
+		gitTag(t, "v1.0.0")
+		writeFile(t, "lib.go", "package lib\n\nimport \"example.com/missing\"\n\nvar Client *missing.Client\n")
+		gitRun(t, "add", ".")
+		gitCommit(t, "fix: lib")
+
+		d := diff(t)
+		require.Len(t, d.TypeErrors, 2)
+		require.Regexp(t, `^v1\.0\.0: lib\.go:3:8: could not import example\.com/missing`, d.TypeErrors[0])
+		require.Regexp(t, `^HEAD: lib\.go:3:8: could not import example\.com/missing`, d.TypeErrors[1])
+	})
+}
+
+func TestNextVerifyAPI(t *testing.T) {
+	tempRepo(t)
+	writeFile(t, "lib.go", "package lib\n\nfunc New() {}\n")
+	gitRun(t, "add", ".")
+	gitCommit(t, "feat: lib")
+	gitTag(t, "v1.0.0")
+	writeFile(t, "lib.go", "package lib\n\nfunc New(name string) {}\n")
+	gitRun(t, "add", ".")
+	gitCommit(t, "fix: take a name")
+
+	result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+	require.NoError(t, err)
+	require.Nil(t, result.API)
+
+	result, err = Next(context.Background(), Options{Prefix: "v", Branch: "main", VerifyAPI: APIVerificationWarn})
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.1", result.Tag)
+	require.True(t, result.APIBumpTooLow())
+	require.Equal(t, BumpMajor, result.API.Bump)
+
+	_, err = Next(context.Background(), Options{Prefix: "v", Branch: "main", VerifyAPI: APIVerificationFail})
+	var apiErr *APIBumpError
+	require.ErrorAs(t, err, &apiErr)
+	require.EqualError(t, err, "the API changes require a major bump, but the commits warrant patch: lib.New changed from func() to func(string)")
+
+	result, err = Next(context.Background(), Options{Prefix: "v", Branch: "main", Bump: BumpMajor, VerifyAPI: APIVerificationFail})
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", result.Tag)
+	require.False(t, result.APIBumpTooLow())
+}
+
+func writeFile(tb testing.TB, name, content string) {
+	tb.Helper()
+	require.NoError(tb, os.MkdirAll(filepath.Dir(name), 0o755))
+	require.NoError(tb, os.WriteFile(name, []byte(content), 0o644))
+}
@@ -0,0 +1,180 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// ReleasePoints decides which commits of the first-parent line Backfill
+// considers as releases.
+type ReleasePoints string
+
+const (
+	// ReleasePointsMerges releases at merge commits, such as the ones of pull
+	// requests, warranting a bump. This is the default.
+	ReleasePointsMerges ReleasePoints = "merges"
+	// ReleasePointsCommits releases at every commit warranting a bump.
+	ReleasePointsCommits ReleasePoints = "commits"
+)
+
+// BackfillOptions configures Backfill.
+type BackfillOptions struct {
+	// From is the revision to start after, whose version is the one of the
+	// latest stable tag reachable from it. By default, Backfill starts after
+	// the latest stable tag reachable from Options.Ref, or from the first
+	// commit when there is none.
+	From string
+	// ReleasePoints are the commits given a version. The zero value means
+	// ReleasePointsMerges.
+	ReleasePoints ReleasePoints
+}
+
+// PlannedTag is a tag Backfill plans to create.
+type PlannedTag struct {
+	Tag     string
+	Version semver.Version
+	// Commit is the release point to tag.
+	Commit Commit
+	// Bump is the increment from the previous release, warranted by the
+	// commits since.
+	Bump Bump
+}
+
+// Backfill plans the tags a repository adopting semtag late would have had.
+// It replays the first-parent line of Options.Ref from BackfillOptions.From,
+// giving each release point the version the commits since the previous one
+// warrant, as Next would have. Release points already having a stable version
+// tag are kept as they are. Pre-release suffixes, Options.ReleaseAs and
+// Options.Bump don't apply.
+func Backfill(ctx context.Context, opts Options, backfillOpts BackfillOptions) ([]PlannedTag, error) {
+	points := backfillOpts.ReleasePoints
+	switch points {
+	case "":
+		points = ReleasePointsMerges
+	case ReleasePointsMerges, ReleasePointsCommits:
+	default:
+		return nil, fmt.Errorf("invalid release points '%s', expected merges or commits", points)
+	}
+
+	// The whole history is replayed, so shallow clones are fully fetched.
+	if err := prepare(ctx, opts, func() (bool, error) { return false, nil }); err != nil {
+		return nil, err
+	}
+
+	tagRev := backfillOpts.From
+	if tagRev == "" {
+		tagRev = opts.ref()
+	}
+	stableTag, err := git.DescribeStableTag(ctx, git.TagModeCurrent, tagRev, opts.Prefix, opts.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stable tag: %w", err)
+	}
+	current, err := versionFromTag(stableTag, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	previous := backfillOpts.From
+	if previous == "" && stableTag != "" {
+		previous = "tags/" + stableTag
+	}
+
+	tagged, err := stableVersionsByCommit(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	line, err := git.FirstParentLine(ctx, previous, opts.ref())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the first-parent history: %w", err)
+	}
+
+	opts.ReleaseAs, opts.Bump = "", BumpNone
+	var plan []PlannedTag
+	for _, point := range line {
+		if version, ok := tagged[point.SHA]; ok {
+			current, previous = version, point.SHA
+			continue
+		}
+		if points == ReleasePointsMerges && len(point.Parents) < 2 {
+			continue
+		}
+
+		commits, err := commitsSince(ctx, previous, point.SHA, opts)
+		if err != nil {
+			return nil, err
+		}
+		decided, err := decide(current, commitList(commits), opts)
+		if err != nil {
+			return nil, err
+		}
+		if decided.bump == BumpNone && decided.override == OverrideNone {
+			continue
+		}
+
+		tag := formatTag(opts.Prefix, decided.version)
+		exists, err := git.HasTag(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("tag %s planned for %s already exists on another commit", tag, shortSHA(point.SHA))
+		}
+
+		plan = append(plan, PlannedTag{
+			Tag:     tag,
+			Version: decided.version,
+			Commit:  point,
+			Bump:    decided.bump,
+		})
+		current, previous = &decided.version, point.SHA
+	}
+	return plan, nil
+}
+
+// CreateTags creates the tags planned by Backfill, as lightweight tags keeping
+// the dates of their commits.
+func CreateTags(ctx context.Context, plan []PlannedTag) error {
+	for _, planned := range plan {
+		if err := git.CreateTag(ctx, planned.Tag, planned.Commit.SHA); err != nil {
+			return fmt.Errorf("failed to create tag %s: %w", planned.Tag, err)
+		}
+	}
+	return nil
+}
+
+// stableVersionsByCommit returns the highest stable version tagged on each
+// commit, among the tags matching opts.Prefix and opts.Pattern. Stable tags
+// are told apart by git.IsStableTag, like DescribeStableTag does.
+func stableVersionsByCommit(ctx context.Context, opts Options) (map[string]*semver.Version, error) {
+	releases, err := History(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]*semver.Version{}
+	for _, release := range releases {
+		if _, ok := versions[release.Commit]; ok || !git.IsStableTag(release.Tag) {
+			continue
+		}
+		version, err := semver.NewVersion(release.Version)
+		if err != nil {
+			return nil, err
+		}
+		versions[release.Commit] = version
+	}
+	return versions, nil
+}
+
+// commitsSince returns the commits of commitsBetween reachable from rev but
+// not from base, which is any revision rather than a tag, or all of them when
+// base is empty.
+func commitsSince(ctx context.Context, base, rev string, opts Options) ([]git.Commit, error) {
+	if base != "" {
+		// Without a tag, the commits reachable from rev are read, which can
+		// be a range.
+		rev = base + ".." + rev
+	}
+	return commitsBetween(ctx, "", rev, opts)
+}
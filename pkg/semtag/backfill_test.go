@@ -0,0 +1,99 @@
+package semtag
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	merge := func(tb testing.TB, branch string, messages ...string) {
+		tb.Helper()
+		gitRun(tb, "switch", "-c", branch)
+		for _, msg := range messages {
+			gitCommit(tb, msg)
+		}
+		gitRun(tb, "switch", "main")
+		gitRun(tb, "merge", "--no-ff", "-m", "Merge branch '"+branch+"'", branch)
+	}
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "chore: init")
+		gitTag(tb, "v0.1.0")
+		merge(tb, "a", "fix: a")
+		gitCommit(tb, "docs: direct")
+		merge(tb, "b", "docs: b")
+		merge(tb, "c", "feat: c", "fix: c")
+		gitCommit(tb, "fix: direct")
+	}
+	tags := func(plan []PlannedTag) []string {
+		var tags []string
+		for _, p := range plan {
+			tags = append(tags, p.Tag+" "+p.Bump.String()+" "+p.Commit.Title)
+		}
+		return tags
+	}
+
+	t.Run("merges", func(t *testing.T) {
+		setup(t)
+		plan, err := Backfill(context.Background(), Options{Prefix: "v"}, BackfillOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"v0.1.1 patch Merge branch 'a'",
+			"v0.2.0 minor Merge branch 'c'",
+		}, tags(plan))
+	})
+
+	t.Run("commits", func(t *testing.T) {
+		setup(t)
+		plan, err := Backfill(context.Background(), Options{Prefix: "v"}, BackfillOptions{ReleasePoints: ReleasePointsCommits})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"v0.1.1 patch Merge branch 'a'",
+			"v0.2.0 minor Merge branch 'c'",
+			"v0.2.1 patch fix: direct",
+		}, tags(plan))
+	})
+
+	t.Run("existing tags and start", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v0.3.0")
+		gitRun(t, "tag", "v0.5.0", "HEAD~1")
+
+		plan, err := Backfill(context.Background(), Options{Prefix: "v", Ref: "HEAD~1"}, BackfillOptions{From: "HEAD~3"})
+		require.NoError(t, err)
+		require.Empty(t, plan)
+
+		gitRun(t, "tag", "-d", "v0.5.0", "v0.3.0")
+		plan, err = Backfill(context.Background(), Options{Prefix: "v"}, BackfillOptions{From: "HEAD~2"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"v0.2.0 minor Merge branch 'c'"}, tags(plan))
+
+		require.NoError(t, CreateTags(context.Background(), plan))
+		require.Equal(t, plan[0].Commit.SHA, strings.TrimSpace(gitRun(t, "rev-list", "-n1", "v0.2.0")))
+
+		_, err = Backfill(context.Background(), Options{Prefix: "v"}, BackfillOptions{From: "HEAD~2", ReleasePoints: "weekly"})
+		require.EqualError(t, err, "invalid release points 'weekly', expected merges or commits")
+	})
+
+	t.Run("numeric suffix is stable", func(t *testing.T) {
+		// v0.1.1-1 is where the version is read from, so merge a is
+		// already released.
+		setup(t)
+		gitRun(t, "tag", "v0.1.1-1", "HEAD~4")
+		plan, err := Backfill(context.Background(), Options{Prefix: "v"}, BackfillOptions{From: "HEAD~5"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"v0.2.0 minor Merge branch 'c'"}, tags(plan))
+	})
+
+	t.Run("conflicting tag", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v0.1.1")
+		_, err := Backfill(context.Background(), Options{Prefix: "v", Ref: "HEAD~1"}, BackfillOptions{})
+		require.ErrorContains(t, err, "tag v0.1.1 planned for")
+		require.ErrorContains(t, err, "already exists on another commit")
+	})
+}
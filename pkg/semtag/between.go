@@ -0,0 +1,94 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// Comparison details the changes between two version tags, as computed by
+// Between.
+type Comparison struct {
+	From string
+	To   string
+	// Version is the version the commits between the tags warrant from the
+	// version of From, as Next would have computed it, and Bump the increment
+	// it is.
+	Version semver.Version
+	Bump    Bump
+	// Commit is the commit which decided Bump, nil for BumpNone.
+	Commit *Commit
+	// Override is OverrideTrailer when a Release-As footer forced Version.
+	Override Override
+	// Tagged is the increment between the versions of From and To, which
+	// differs from Bump when the release was forced or the tag is off.
+	Tagged Bump
+	// Commits are the commits reachable from To but not from From, read
+	// following Options.Paths and Options.MergeStrategy, newest first,
+	// including the ones left out by the scope filters.
+	Commits []ExplainedCommit
+}
+
+// Between compares two version tags, to audit past releases: it aggregates
+// the commits between them as Next would have, regardless of opts.Ref,
+// opts.ReleaseAs and opts.Bump.
+func Between(ctx context.Context, opts Options, from, to string) (*Comparison, error) {
+	if err := prepare(ctx, opts, func() (bool, error) {
+		_, err := git.MergeBase(ctx, "tags/"+from, "tags/"+to)
+		return err == nil, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []string{from, to} {
+		ok, err := git.HasTag(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("tag '%s' not found", tag)
+		}
+	}
+
+	fromVersion, err := versionFromTag(from, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := versionFromTag(to, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.ReleaseAs, opts.Bump = "", BumpNone
+	source, err := newHistorySource(ctx, from, "tags/"+to, opts)
+	if err != nil {
+		return nil, err
+	}
+	decided, err := decide(fromVersion, source, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := readCommits(ctx, from, "tags/"+to, opts)
+	if err != nil {
+		return nil, err
+	}
+	explained, err := explainCommits(commits, opts, decided.commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{
+		From:     from,
+		To:       to,
+		Version:  decided.version,
+		Bump:     decided.bump,
+		Commit:   decided.commit,
+		Override: decided.override,
+		Tagged:   bumpBetween(fromVersion, toVersion),
+		Commits:  explained,
+	}, nil
+}
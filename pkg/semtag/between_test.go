@@ -0,0 +1,43 @@
+package semtag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBetween(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: first")
+	gitTag(t, "v1.2.0")
+	gitCommit(t, "fix: a")
+	gitTag(t, "v1.2.1")
+	gitCommit(t, "feat(cli): b")
+	gitCommit(t, "docs: c")
+	gitTag(t, "v1.4.0")
+	gitCommit(t, "feat!: d")
+
+	comparison, err := Between(context.Background(), Options{Prefix: "v", Bump: BumpMajor}, "v1.2.0", "v1.4.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", comparison.Version.String())
+	require.Equal(t, BumpMinor, comparison.Bump)
+	require.Equal(t, "feat(cli): b", comparison.Commit.Title)
+	require.Equal(t, BumpMinor, comparison.Tagged)
+	require.Len(t, comparison.Commits, 3)
+	require.True(t, comparison.Commits[1].Decisive)
+	require.Equal(t, BumpPatch, comparison.Commits[2].Bump)
+
+	t.Run("filters", func(t *testing.T) {
+		comparison, err := Between(context.Background(), Options{Prefix: "v", ExcludeScopes: []string{"cli"}}, "v1.2.1", "v1.4.0")
+		require.NoError(t, err)
+		require.Equal(t, BumpNone, comparison.Bump)
+		require.Nil(t, comparison.Commit)
+		require.Equal(t, "scope excluded", comparison.Commits[1].Excluded)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		_, err := Between(context.Background(), Options{Prefix: "v"}, "v1.2.0", "v9.9.9")
+		require.EqualError(t, err, "tag 'v9.9.9' not found")
+	})
+}
@@ -0,0 +1,158 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google-internal/semtag/internal/changelog"
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// DefaultChangelogSections maps commit types to the Keep a Changelog
+// sections their entries go to. Commits of other types are left out.
+var DefaultChangelogSections = map[string]string{
+	"feat":      "Added",
+	"perf":      "Changed",
+	"refactor":  "Changed",
+	"deprecate": "Deprecated",
+	"revert":    "Removed",
+	"remove":    "Removed",
+	"fix":       "Fixed",
+	"security":  "Security",
+}
+
+// ChangelogOptions configures UpdateChangelog.
+type ChangelogOptions struct {
+	// Sections maps commit types to changelog sections, nil meaning
+	// DefaultChangelogSections.
+	Sections map[string]string
+	// RepositoryURL is the web URL of the repository, used to create the
+	// compare links when the changelog has none yet. When empty, it is
+	// derived from the origin remote.
+	RepositoryURL string
+	// Date is the release date, today when zero.
+	Date time.Time
+}
+
+// UpdateChangelog updates a changelog following the Keep a Changelog format
+// for the next version, as computed by Next: the [Unreleased] changes are
+// moved under the version, together with the commits since the previous
+// release, and the compare links are updated. The changelog is returned
+// unchanged when the version is already in it, and ErrNoReleaseNeeded is
+// returned when no commit warrants a new version.
+func UpdateChangelog(ctx context.Context, opts Options, changelogOpts ChangelogOptions, content string) (string, error) {
+	result, err := Next(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if !result.ReleaseNeeded() {
+		return "", fmt.Errorf("%w: %s", ErrNoReleaseNeeded, result.Reason())
+	}
+
+	commits, err := commitsBetween(ctx, result.PreviousTag, opts.ref(), opts)
+	if err != nil {
+		return "", err
+	}
+
+	repositoryURL := changelogOpts.RepositoryURL
+	if repositoryURL == "" {
+		repositoryURL, _ = RepositoryURL(ctx)
+	}
+
+	linkOpts := opts.Links
+	if linkOpts.RepositoryURL == "" {
+		linkOpts.RepositoryURL = repositoryURL
+	}
+	links, err := compileLinks(linkOpts, linkOpts.RepositoryURL)
+	if err != nil {
+		return "", err
+	}
+
+	date := changelogOpts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	return updateChangelog(content, commits, changelogOpts.Sections, links, changelog.ReleaseOptions{
+		Version:       result.Version.String(),
+		Date:          date.Format(time.DateOnly),
+		Tag:           result.Tag,
+		PreviousTag:   result.PreviousTag,
+		RepositoryURL: repositoryURL,
+	}), nil
+}
+
+func updateChangelog(content string, commits []git.Commit, sections map[string]string, links *linker, opts changelog.ReleaseOptions) string {
+	if sections == nil {
+		sections = DefaultChangelogSections
+	}
+
+	if strings.TrimSpace(content) == "" {
+		content = changelogHeader
+	}
+	c := changelog.Parse(content)
+
+	release, created := c.Cut(opts)
+	if !created {
+		return content
+	}
+
+	// Commits are newest first, entries read better oldest first.
+	for i := len(commits) - 1; i >= 0; i-- {
+		parsed := parseCommit(commits[i])
+		section, ok := sections[parsed.Type]
+		if !ok {
+			continue
+		}
+		description, refs := links.references(commits[i], parsed)
+		entry := description + markdownReferences(refs)
+		if parsed.Scope != "" {
+			entry = "**" + parsed.Scope + ":** " + entry
+		}
+		release.AddEntry(section, entry)
+	}
+	return c.String()
+}
+
+const changelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+## [Unreleased]
+`
+
+// RepositoryURL returns the web URL of the repository on its forge, derived
+// from the origin remote, e.g. https://github.com/owner/repo. It is empty
+// when the remote is not hosted on a forge.
+func RepositoryURL(ctx context.Context) (string, error) {
+	remote, err := git.RemoteURL(ctx, "origin")
+	if err != nil {
+		return "", err
+	}
+	return webURL(remote), nil
+}
+
+var scpLikeURL = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+):(.+)$`)
+
+// webURL turns the URL of a git remote into the URL of the repository on its
+// forge, e.g. git@github.com:owner/repo.git into https://github.com/owner/repo.
+func webURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		if u.Host == "" {
+			return ""
+		}
+		return "https://" + u.Hostname() + strings.TrimSuffix(u.Path, "/")
+	}
+	if m := scpLikeURL.FindStringSubmatch(remote); m != nil {
+		return "https://" + m[1] + "/" + strings.TrimPrefix(m[2], "/")
+	}
+	return ""
+}
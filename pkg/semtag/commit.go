@@ -0,0 +1,89 @@
+package semtag
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+var (
+	header      = regexp.MustCompile(`^(\w[\w-]*)(?:\(([^)]*)\))?(!)?: (.*)$`)
+	footerToken = regexp.MustCompile(`^(BREAKING[ -]CHANGE|[\w-]+)(?:: | #)(.*)$`)
+)
+
+// footer is a "Token: value" (or "Token #value") line at the end of a commit
+// message, as described by Conventional Commits and git-interpret-trailers.
+type footer struct {
+	Token string
+	Value string
+}
+
+// conventionalCommit is a commit message parsed following Conventional
+// Commits. Titles which don't follow it have an empty Type.
+type conventionalCommit struct {
+	Type        string
+	Scope       string
+	Description string
+	// Breaking is set by a "!" before the colon; BREAKING CHANGE footers are
+	// in Footers.
+	Breaking bool
+	Footers  []footer
+}
+
+func parseCommit(commit git.Commit) conventionalCommit {
+	var parsed conventionalCommit
+	if m := header.FindStringSubmatch(strings.TrimSpace(commit.Title)); m != nil {
+		parsed.Type = strings.ToLower(m[1])
+		parsed.Scope = strings.TrimSpace(m[2])
+		parsed.Breaking = m[3] == "!"
+		parsed.Description = strings.TrimSpace(m[4])
+	} else {
+		parsed.Description = strings.TrimSpace(commit.Title)
+	}
+	parsed.Footers = parseFooters(commit.Body)
+	return parsed
+}
+
+// parseFooters returns the footers of a commit body. They start with the
+// first paragraph beginning with a footer token and go on until the end of
+// the message; a footer's value spans until the next token.
+func parseFooters(body string) []footer {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	start := -1
+	for i, line := range lines {
+		if (i == 0 || strings.TrimSpace(lines[i-1]) == "") && footerToken.MatchString(line) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	var footers []footer
+	for _, line := range lines[start:] {
+		if m := footerToken.FindStringSubmatch(line); m != nil {
+			footers = append(footers, footer{Token: m[1], Value: m[2]})
+			continue
+		}
+		last := &footers[len(footers)-1]
+		last.Value += "\n" + line
+	}
+	for i := range footers {
+		footers[i].Value = strings.TrimSpace(footers[i].Value)
+	}
+	return footers
+}
+
+// footer returns the value of the first footer with the given token, compared
+// case-insensitively.
+func (c conventionalCommit) footer(token string) (string, bool) {
+	for _, f := range c.Footers {
+		if strings.EqualFold(f.Token, token) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
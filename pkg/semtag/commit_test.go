@@ -0,0 +1,45 @@
+package semtag
+
+import (
+	"testing"
+
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommit(t *testing.T) {
+	for title, expected := range map[string]conventionalCommit{
+		"feat: foo":             {Type: "feat", Description: "foo"},
+		"fix(api)!: bar":        {Type: "fix", Scope: "api", Breaking: true, Description: "bar"},
+		"Feat(Cli): baz":        {Type: "feat", Scope: "Cli", Description: "baz"},
+		"Merge branch 'main'":   {Description: "Merge branch 'main'"},
+		"chore(deps-dev): bump": {Type: "chore", Scope: "deps-dev", Description: "bump"},
+	} {
+		t.Run(title, func(t *testing.T) {
+			require.Equal(t, expected, parseCommit(git.Commit{Title: title}))
+		})
+	}
+}
+
+func TestParseFooters(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		require.Empty(t, parseFooters("some text\n\nmore text"))
+	})
+
+	t.Run("trailers", func(t *testing.T) {
+		body := "Some explanation.\n\nRelease-As: 2.0.0\nSigned-off-by: Foo <foo@example.com>\nRefs #123\n"
+		require.Equal(t, []footer{
+			{Token: "Release-As", Value: "2.0.0"},
+			{Token: "Signed-off-by", Value: "Foo <foo@example.com>"},
+			{Token: "Refs", Value: "123"},
+		}, parseFooters(body))
+	})
+
+	t.Run("multi-line breaking change", func(t *testing.T) {
+		body := "BREAKING CHANGE: the config\nformat changed.\n\nReviewed-by: Bar"
+		require.Equal(t, []footer{
+			{Token: "BREAKING CHANGE", Value: "the config\nformat changed."},
+			{Token: "Reviewed-by", Value: "Bar"},
+		}, parseFooters(body))
+	})
+}
@@ -0,0 +1,86 @@
+package semtag
+
+import (
+	"context"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// Explanation details how Next computed a version.
+type Explanation struct {
+	Result *Result
+	// Commits are the commits since the previous stable tag, read following
+	// Options.Paths and Options.MergeStrategy, newest first, including the
+	// ones left out by the scope filters.
+	Commits []ExplainedCommit
+}
+
+// ExplainedCommit is a commit and what it means for the version.
+type ExplainedCommit struct {
+	Commit Commit
+	Type   string
+	Scope  string
+	// Bump is the bump the commit warrants by itself with Options.Rules,
+	// BumpNone when it is excluded.
+	Bump Bump
+	// Excluded tells why the commit is not considered, e.g. "scope
+	// excluded". It is empty for the commits which are.
+	Excluded string
+	// ReleaseAs is the version forced by a Release-As footer of the commit.
+	ReleaseAs string
+	// Decisive is true for the commit which decided the version.
+	Decisive bool
+}
+
+// Explain computes the next version as Next does, and details how each commit
+// since the previous stable tag was taken into account. The version is
+// explained even when it fails Validate, opts.Policy or opts.VerifyAPI.
+func Explain(ctx context.Context, opts Options) (*Explanation, error) {
+	opts.SkipValidation, opts.Policy = true, Policy{}
+	if opts.VerifyAPI == APIVerificationFail {
+		opts.VerifyAPI = APIVerificationWarn
+	}
+	result, err := Next(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := readCommits(ctx, result.PreviousTag, opts.ref(), opts)
+	if err != nil {
+		return nil, err
+	}
+	explained, err := explainCommits(commits, opts, result.Commit)
+	if err != nil {
+		return nil, err
+	}
+	return &Explanation{Result: result, Commits: explained}, nil
+}
+
+// explainCommits details what each commit means for a version, which decisive
+// decided.
+func explainCommits(commits []git.Commit, opts Options, decisive *Commit) ([]ExplainedCommit, error) {
+	filter, err := newScopeFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	classifier := opts.Rules.classifier()
+	var explained []ExplainedCommit
+	for _, commit := range commits {
+		parsed := parseCommit(commit)
+		c := ExplainedCommit{
+			Commit:   commit,
+			Type:     parsed.Type,
+			Scope:    parsed.Scope,
+			Decisive: decisive != nil && decisive.SHA == commit.SHA,
+		}
+		if excluded, reason := filter.excludes(commit); excluded {
+			c.Excluded = reason
+		} else {
+			c.Bump = classifier.bump(commit)
+			c.ReleaseAs, _ = parsed.footer(releaseAsToken)
+		}
+		explained = append(explained, c)
+	}
+	return explained, nil
+}
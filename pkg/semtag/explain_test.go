@@ -0,0 +1,45 @@
+package semtag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: foobar")
+	gitTag(t, "v1.2.3")
+	gitCommit(t, "feat(web): page")
+	gitCommit(t, "fix(cli): flag")
+	gitCommit(t, "chore: release 2.0.0\n\nRelease-As: 2.0.0")
+
+	explanation, err := Explain(context.Background(), Options{Prefix: "v", Branch: "main", IncludeScopes: []string{"cli"}})
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.4", explanation.Result.Tag)
+	require.Len(t, explanation.Commits, 3)
+
+	release, fix, feat := explanation.Commits[0], explanation.Commits[1], explanation.Commits[2]
+	require.Equal(t, "scope not included", release.Excluded)
+	require.Empty(t, release.ReleaseAs)
+
+	require.Empty(t, fix.Excluded)
+	require.Equal(t, BumpPatch, fix.Bump)
+	require.Equal(t, "fix", fix.Type)
+	require.Equal(t, "cli", fix.Scope)
+	require.True(t, fix.Decisive)
+
+	require.Equal(t, "scope not included", feat.Excluded)
+	require.Equal(t, BumpNone, feat.Bump)
+	require.False(t, feat.Decisive)
+
+	t.Run("without filters", func(t *testing.T) {
+		explanation, err := Explain(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", explanation.Result.Tag)
+		require.Equal(t, "2.0.0", explanation.Commits[0].ReleaseAs)
+		require.True(t, explanation.Commits[0].Decisive)
+		require.Equal(t, BumpMinor, explanation.Commits[2].Bump)
+	})
+}
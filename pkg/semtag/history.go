@@ -0,0 +1,84 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/gobwas/glob"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// Release is a version tag of the repository.
+type Release struct {
+	Tag     string `json:"tag"`
+	Version string `json:"version"`
+	// Commit is the hash of the tagged commit.
+	Commit string `json:"commit"`
+	// Date is when annotated tags were created, and the commit date of
+	// lightweight ones.
+	Date time.Time `json:"date"`
+	// Tagger is who created annotated tags, and the committer of lightweight
+	// ones.
+	Tagger     Contributor `json:"tagger"`
+	Annotated  bool        `json:"annotated"`
+	Prerelease bool        `json:"prerelease"`
+	// Bump is the increment from the previous version in SemVer order. It is
+	// BumpNone for the first version, and from a pre-release to its release.
+	Bump Bump `json:"bump"`
+}
+
+// History returns the version tags matching opts.Prefix and opts.Pattern,
+// whether reachable from HEAD or not, latest version first. Tags which are not
+// versions once the prefix is removed are left out.
+func History(ctx context.Context, opts Options) ([]Release, error) {
+	if err := git.EnsureRepo(ctx); err != nil {
+		return nil, err
+	}
+
+	var pattern glob.Glob
+	if opts.Pattern != "" {
+		g, err := glob.Compile(opts.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", opts.Pattern, err)
+		}
+		pattern = g
+	}
+
+	tags, err := git.Tags(ctx, opts.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var releases []Release
+	var versions []*semver.Version
+	for _, tag := range tags {
+		if pattern != nil && !pattern.Match(tag.Name) {
+			continue
+		}
+		version, err := semver.NewVersion(strings.TrimPrefix(tag.Name, opts.Prefix))
+		if err != nil {
+			continue
+		}
+		releases = append(releases, Release{
+			Tag:        tag.Name,
+			Version:    version.String(),
+			Commit:     tag.Commit,
+			Date:       tag.Date,
+			Tagger:     Contributor{Name: tag.Tagger.Name, Email: tag.Tagger.Email},
+			Annotated:  tag.Annotated,
+			Prerelease: version.Prerelease() != "",
+		})
+		versions = append(versions, version)
+	}
+
+	for i := range releases {
+		if i+1 < len(releases) {
+			releases[i].Bump = bumpBetween(versions[i+1], versions[i])
+		}
+	}
+	return releases, nil
+}
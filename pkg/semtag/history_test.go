@@ -0,0 +1,56 @@
+package semtag
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: first")
+	gitTag(t, "v0.1.0")
+	gitCommit(t, "feat: second")
+	gitTag(t, "v0.2.0")
+	gitTag(t, "other-1.0.0")
+	gitCommit(t, "feat!: third")
+	gitTag(t, "v1.0.0-rc.1")
+	gitRun(t, "tag", "-a", "v1.0.0", "-m", "release 1.0.0")
+	gitCommit(t, "fix: fourth")
+	gitTag(t, "v1.0.1")
+	gitTag(t, "vnext")
+
+	releases, err := History(context.Background(), Options{Prefix: "v", Pattern: "v*"})
+	require.NoError(t, err)
+
+	type summary struct {
+		tag        string
+		bump       Bump
+		prerelease bool
+		annotated  bool
+	}
+	var summaries []summary
+	for _, r := range releases {
+		summaries = append(summaries, summary{r.Tag, r.Bump, r.Prerelease, r.Annotated})
+	}
+	require.Equal(t, []summary{
+		{"v1.0.1", BumpPatch, false, false},
+		{"v1.0.0", BumpNone, false, true},
+		{"v1.0.0-rc.1", BumpMajor, true, false},
+		{"v0.2.0", BumpMinor, false, false},
+		{"v0.1.0", BumpNone, false, false},
+	}, summaries)
+
+	require.Equal(t, "1.0.1", releases[0].Version)
+	require.Equal(t, releases[1].Commit, releases[2].Commit)
+	require.NotEqual(t, releases[0].Commit, releases[1].Commit)
+	require.Equal(t, "svu", releases[0].Tagger.Name)
+	require.False(t, releases[0].Date.IsZero())
+
+	data, err := json.Marshal(releases[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"bump":"patch"`)
+	require.Contains(t, string(data), `"tagger":{"name":"svu","email":"svu@example.com"}`)
+}
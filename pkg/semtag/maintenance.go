@@ -0,0 +1,151 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/gobwas/glob"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// MaintenanceBranch is a branch maintaining an older line of versions, such as
+// release/1.x releasing 1.x.y versions while main is on 2.x.
+type MaintenanceBranch struct {
+	// Pattern is a glob matching the names of the branches, e.g. "release/*".
+	Pattern string
+	// Line is the versions released from the branches: "1.x" allows minor and
+	// patch bumps of 1.y.z, and "1.8.x" only patch bumps of 1.8.z. When
+	// empty, it is the last part of the branch name, as in release/1.x.
+	Line string
+}
+
+// MaintenanceError is returned when the commits of a maintenance branch
+// warrant a version outside of its line, or a version already tagged, e.g. on
+// main.
+type MaintenanceError struct {
+	Branch string
+	Line   string
+	// Version is the refused version, and Bump the increment it is.
+	Version semver.Version
+	Bump    Bump
+	// Commit is the commit which decided the version, nil when forced
+	// through Options.
+	Commit *Commit
+	// Tagged is set when the version is refused for being already tagged.
+	Tagged bool
+}
+
+func (e *MaintenanceError) Error() string {
+	var msg string
+	if e.Tagged {
+		msg = fmt.Sprintf("version %s of maintenance branch %s is already tagged elsewhere", &e.Version, e.Branch)
+	} else {
+		msg = fmt.Sprintf("version %s is outside the %s line of maintenance branch %s", &e.Version, e.Line, e.Branch)
+	}
+	if e.Commit != nil {
+		msg += fmt.Sprintf(": %s bump by %s %s", e.Bump, shortSHA(e.Commit.SHA), e.Commit.Title)
+	}
+	return msg
+}
+
+// maintenanceLine is a parsed MaintenanceBranch.Line.
+type maintenanceLine struct {
+	name  string
+	major uint64
+	// minor is -1 when minor bumps are allowed.
+	minor int64
+}
+
+// parseMaintenanceLine parses a line such as "1.x", "v1.x.x" or "1.8.x".
+func parseMaintenanceLine(line string) (maintenanceLine, error) {
+	invalid := fmt.Errorf("invalid maintenance line '%s', expected e.g. 1.x or 1.8.x", line)
+	parts := strings.Split(strings.TrimPrefix(strings.ToLower(line), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 || parts[len(parts)-1] != "x" {
+		return maintenanceLine{}, invalid
+	}
+	major, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return maintenanceLine{}, invalid
+	}
+	l := maintenanceLine{name: line, major: major, minor: -1}
+	if len(parts) == 3 && parts[1] != "x" {
+		minor, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || minor < 0 {
+			return maintenanceLine{}, invalid
+		}
+		l.minor = minor
+	}
+	return l, nil
+}
+
+func (l maintenanceLine) contains(version semver.Version) bool {
+	return version.Major() == l.major && (l.minor < 0 || version.Minor() == uint64(l.minor))
+}
+
+// maintenanceLineOf returns the line of the first maintenance branch matching
+// branch, or nil when none does.
+func maintenanceLineOf(branches []MaintenanceBranch, branch string) (*maintenanceLine, error) {
+	for _, b := range branches {
+		g, err := glob.Compile(b.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance branch pattern '%s': %w", b.Pattern, err)
+		}
+		if !g.Match(branch) {
+			continue
+		}
+		name := b.Line
+		if name == "" {
+			name = path.Base(branch)
+		}
+		line, err := parseMaintenanceLine(name)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance branch %s: %w", branch, err)
+		}
+		return &line, nil
+	}
+	return nil, nil
+}
+
+// maintenanceLineFor returns the branch of opts.Ref and its line when it is a
+// maintenance branch, nil otherwise. Pull request previews have none.
+func maintenanceLineFor(ctx context.Context, opts Options) (*maintenanceLine, string, error) {
+	if len(opts.MaintenanceBranches) == 0 || opts.PullRequest.Enabled {
+		return nil, "", nil
+	}
+	branch := opts.branch(ctx)
+	line, err := maintenanceLineOf(opts.MaintenanceBranches, branch)
+	return line, branch, err
+}
+
+// checkMaintenance refuses a decided version outside of the line of the
+// maintenance branch, or already tagged.
+func checkMaintenance(ctx context.Context, line maintenanceLine, branch string, decided decision, opts Options) error {
+	if decided.bump == BumpNone && decided.override == OverrideNone {
+		return nil
+	}
+
+	maintenanceErr := &MaintenanceError{
+		Branch:  branch,
+		Line:    line.name,
+		Version: decided.version,
+		Bump:    decided.bump,
+		Commit:  decided.commit,
+	}
+	if !line.contains(decided.version) {
+		return maintenanceErr
+	}
+	tagged, err := git.HasTag(ctx, formatTag(opts.Prefix, decided.version))
+	if err != nil {
+		return err
+	}
+	if tagged {
+		maintenanceErr.Tagged = true
+		return maintenanceErr
+	}
+	return nil
+}
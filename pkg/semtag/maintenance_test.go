@@ -0,0 +1,128 @@
+package semtag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceLine(t *testing.T) {
+	for line, expected := range map[string]maintenanceLine{
+		"1.x":    {name: "1.x", major: 1, minor: -1},
+		"v2.x.x": {name: "v2.x.x", major: 2, minor: -1},
+		"1.8.x":  {name: "1.8.x", major: 1, minor: 8},
+	} {
+		t.Run(line, func(t *testing.T) {
+			parsed, err := parseMaintenanceLine(line)
+			require.NoError(t, err)
+			require.Equal(t, expected, parsed)
+		})
+	}
+
+	for _, line := range []string{"1", "1.8", "1.8.3", "x.x", "main", "1.x.3"} {
+		t.Run(line, func(t *testing.T) {
+			_, err := parseMaintenanceLine(line)
+			require.Error(t, err)
+		})
+	}
+
+	line, err := parseMaintenanceLine("1.8.x")
+	require.NoError(t, err)
+	require.True(t, line.contains(*semver.MustParse("1.8.3")))
+	require.False(t, line.contains(*semver.MustParse("1.9.0")))
+	require.False(t, line.contains(*semver.MustParse("2.8.0")))
+}
+
+func TestNextMaintenanceBranch(t *testing.T) {
+	setup := func(tb testing.TB, branch string) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "feat: first")
+		gitTag(tb, "v1.8.2")
+		gitRun(tb, "switch", "-c", branch)
+		gitRun(tb, "switch", "main")
+		gitCommit(tb, "feat: second")
+		gitTag(tb, "v1.9.0")
+		gitCommit(tb, "feat!: third")
+		gitTag(tb, "v2.0.0")
+		gitRun(tb, "switch", branch)
+	}
+	maintenance := []MaintenanceBranch{{Pattern: "release/*"}}
+
+	t.Run("fix", func(t *testing.T) {
+		setup(t, "release/1.8.x")
+		gitCommit(t, "fix: backport")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		require.NoError(t, err)
+		// Maintenance branches release stable versions.
+		require.Equal(t, "v1.8.3", result.Tag)
+	})
+
+	t.Run("no release needed", func(t *testing.T) {
+		setup(t, "release/1.8.x")
+		gitCommit(t, "docs: backport")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		require.NoError(t, err)
+		require.Equal(t, "v1.8.2", result.Tag)
+	})
+
+	t.Run("outside of the line", func(t *testing.T) {
+		setup(t, "release/1.8.x")
+		gitCommit(t, "feat: backport")
+		gitCommit(t, "fix: backport")
+
+		_, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		var lineErr *MaintenanceError
+		require.ErrorAs(t, err, &lineErr)
+		require.Equal(t, "release/1.8.x", lineErr.Branch)
+		require.Equal(t, "1.9.0", lineErr.Version.String())
+		require.Equal(t, BumpMinor, lineErr.Bump)
+		require.False(t, lineErr.Tagged)
+		require.Regexp(t, `^version 1\.9\.0 is outside the 1\.8\.x line of maintenance branch release/1\.8\.x: minor bump by [0-9a-f]{7} feat: backport$`, err.Error())
+	})
+
+	t.Run("breaking change", func(t *testing.T) {
+		setup(t, "support/legacy")
+		gitCommit(t, "fix!: backport")
+
+		_, err := Next(context.Background(), Options{
+			Prefix:              "v",
+			MaintenanceBranches: []MaintenanceBranch{{Pattern: "support/*", Line: "1.x"}},
+		})
+		var lineErr *MaintenanceError
+		require.ErrorAs(t, err, &lineErr)
+		require.Equal(t, "2.0.0", lineErr.Version.String())
+	})
+
+	t.Run("tagged on main", func(t *testing.T) {
+		setup(t, "release/1.x")
+		gitCommit(t, "feat: backport")
+
+		_, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		var lineErr *MaintenanceError
+		require.ErrorAs(t, err, &lineErr)
+		require.True(t, lineErr.Tagged)
+		require.Equal(t, "1.9.0", lineErr.Version.String())
+	})
+
+	t.Run("other branches", func(t *testing.T) {
+		setup(t, "release/1.x")
+		gitRun(t, "switch", "main")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		setup(t, "release/next")
+		gitCommit(t, "fix: backport")
+
+		_, err := Next(context.Background(), Options{Prefix: "v", MaintenanceBranches: maintenance})
+		require.ErrorContains(t, err, "invalid maintenance line 'next'")
+	})
+}
@@ -0,0 +1,82 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// MergeStrategy decides how merge commits and the commits of merged branches
+// are taken into account.
+type MergeStrategy string
+
+const (
+	// MergeStrategyAll considers every commit, including the ones of merged
+	// branches. Merge commits are classified like the others, which usually
+	// changes nothing as their titles don't follow Conventional Commits. This
+	// is the default.
+	MergeStrategyAll MergeStrategy = "all"
+	// MergeStrategyFirstParent only considers the commits of the branch
+	// itself, such as squash merges, leaving out the ones of merged branches.
+	MergeStrategyFirstParent MergeStrategy = "first-parent"
+	// MergeStrategyMergeBody is MergeStrategyFirstParent, with the title of
+	// merge commits taken from their body, where GitHub and GitLab put the
+	// title of the pull request: "Merge pull request #12 from foo/feat-x"
+	// followed by "feat: x" counts as "feat: x".
+	MergeStrategyMergeBody MergeStrategy = "merge-body"
+)
+
+// MergeStrategies are the valid merge strategies.
+var MergeStrategies = []MergeStrategy{MergeStrategyAll, MergeStrategyFirstParent, MergeStrategyMergeBody}
+
+// ParseMergeStrategy parses the name of a merge strategy. An empty name is
+// MergeStrategyAll.
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	if name == "" {
+		return MergeStrategyAll, nil
+	}
+	for _, s := range MergeStrategies {
+		if strings.EqualFold(name, string(s)) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("invalid merge strategy '%s', expected all, first-parent or merge-body", name)
+}
+
+// readCommits returns the commits reachable from rev but not from tag (all of
+// them when tag is empty) touching opts.Paths, following opts.MergeStrategy.
+func readCommits(ctx context.Context, tag, rev string, opts Options) ([]git.Commit, error) {
+	strategy, err := ParseMergeStrategy(string(opts.MergeStrategy))
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := git.ChangelogBetween(ctx, tag, rev, opts.Paths, strategy != MergeStrategyAll)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changelog: %w", err)
+	}
+	if strategy == MergeStrategyMergeBody {
+		for i := range commits {
+			commits[i] = titleFromMergeBody(commits[i])
+		}
+	}
+	return commits, nil
+}
+
+// titleFromMergeBody replaces the title of a merge commit with the first line
+// of its body, if any.
+func titleFromMergeBody(commit git.Commit) git.Commit {
+	if len(commit.Parents) < 2 {
+		return commit
+	}
+	body := strings.TrimLeft(commit.Body, "\r\n\t ")
+	if body == "" {
+		return commit
+	}
+	title, rest, _ := strings.Cut(body, "\n")
+	commit.Title = strings.TrimSpace(title)
+	commit.Body = rest
+	return commit
+}
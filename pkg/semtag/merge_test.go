@@ -0,0 +1,83 @@
+package semtag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+func TestMergeStrategy(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "chore: foobar")
+		gitTag(tb, "v1.2.3")
+		gitRun(tb, "checkout", "-b", "feat-x")
+		gitCommit(tb, "wip")
+		gitCommit(tb, "fix: typo")
+		gitRun(tb, "checkout", "main")
+		gitCommit(tb, "docs: readme")
+		gitRun(tb, "merge", "--no-ff", "feat-x", "-m", "Merge pull request #12 from foo/feat-x", "-m", "feat: add x")
+	}
+
+	for strategy, expected := range map[MergeStrategy]string{
+		"":                       "v1.2.4",
+		MergeStrategyAll:         "v1.2.4",
+		MergeStrategyFirstParent: "v1.2.3",
+		MergeStrategyMergeBody:   "v1.3.0",
+	} {
+		t.Run(string(strategy), func(t *testing.T) {
+			setup(t)
+			result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main", MergeStrategy: strategy})
+			require.NoError(t, err)
+			require.Equal(t, expected, result.Tag)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		setup(t)
+		_, err := Next(context.Background(), Options{Prefix: "v", Branch: "main", MergeStrategy: "octopus"})
+		require.Error(t, err)
+	})
+}
+
+func TestTitleFromMergeBody(t *testing.T) {
+	merge := git.Commit{
+		Title:   "Merge branch 'feat-x' into 'main'",
+		Body:    "\nfeat(cli): add x\n\nSee merge request group/project!3",
+		Parents: []string{"a", "b"},
+	}
+	require.Equal(t, git.Commit{
+		Title:   "feat(cli): add x",
+		Body:    "\nSee merge request group/project!3",
+		Parents: []string{"a", "b"},
+	}, titleFromMergeBody(merge))
+
+	t.Run("not a merge", func(t *testing.T) {
+		commit := git.Commit{Title: "fix: foo", Body: "\nfeat: bar", Parents: []string{"a"}}
+		require.Equal(t, commit, titleFromMergeBody(commit))
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		commit := git.Commit{Title: "Merge branch 'foo'", Parents: []string{"a", "b"}}
+		require.Equal(t, commit, titleFromMergeBody(commit))
+	})
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for name, expected := range map[string]MergeStrategy{
+		"":             MergeStrategyAll,
+		"all":          MergeStrategyAll,
+		"First-Parent": MergeStrategyFirstParent,
+		"merge-body":   MergeStrategyMergeBody,
+	} {
+		strategy, err := ParseMergeStrategy(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, strategy)
+	}
+	_, err := ParseMergeStrategy("squash")
+	require.Error(t, err)
+}
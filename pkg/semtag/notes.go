@@ -0,0 +1,395 @@
+package semtag
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// BuiltinTemplates are the names of the release notes templates shipped with
+// semtag, usable with BuiltinTemplate.
+var BuiltinTemplates = []string{"markdown", "slack", "html"}
+
+// commitTypes are the titles of the usual Conventional Commits types, in the
+// order their groups appear in release notes. Other types come after them.
+var commitTypes = []struct{ Type, Title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"style", "Styles"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Chores"},
+}
+
+// typeEmojis are used by the "emoji" template function.
+var typeEmojis = map[string]string{
+	"feat":     "✨",
+	"fix":      "🐛",
+	"perf":     "⚡",
+	"revert":   "⏪",
+	"refactor": "♻️",
+	"docs":     "📝",
+	"style":    "💄",
+	"test":     "✅",
+	"build":    "📦",
+	"ci":       "👷",
+	"chore":    "🔧",
+}
+
+// ReleaseNotes is the model release notes templates are executed with. It
+// is also what the notes command prints with --json.
+type ReleaseNotes struct {
+	// Version and Tag are the version being released, and PreviousVersion
+	// and PreviousTag the one before it, empty for the first release.
+	Version         string    `json:"version"`
+	Tag             string    `json:"tag"`
+	PreviousVersion string    `json:"previousVersion,omitempty"`
+	PreviousTag     string    `json:"previousTag,omitempty"`
+	Date            time.Time `json:"date"`
+	// Groups are the commits grouped by type.
+	Groups []CommitGroup `json:"groups"`
+	// Breaking are the breaking changes, from "!" titles and BREAKING CHANGE
+	// footers.
+	Breaking []BreakingChange `json:"breaking"`
+	// ClosedIssues are the issues and tracker keys closed by the commits,
+	// e.g. with "Fixes: #123" footers.
+	ClosedIssues []Reference `json:"closedIssues"`
+	// Contributors are the authors and co-authors of the commits, ordered by
+	// name.
+	Contributors []Contributor `json:"contributors"`
+	// Commits are all the commits of the release, newest first.
+	Commits []NoteCommit `json:"commits"`
+}
+
+// CommitGroup are the commits of a type, and the same commits grouped by
+// scope.
+type CommitGroup struct {
+	Type string `json:"type"`
+	// Title is the human readable name of the type, e.g. "Bug Fixes". It is
+	// "Other Changes" for commits not following Conventional Commits.
+	Title   string       `json:"title"`
+	Commits []NoteCommit `json:"commits"`
+	// Scopes are ordered by name, with commits without scope first.
+	Scopes []ScopeGroup `json:"scopes"`
+}
+
+// ScopeGroup are the commits of a type with the same scope.
+type ScopeGroup struct {
+	Scope   string       `json:"scope"`
+	Commits []NoteCommit `json:"commits"`
+}
+
+// NoteCommit is a commit as presented in release notes.
+type NoteCommit struct {
+	SHA         string      `json:"sha"`
+	ShortSHA    string      `json:"shortSha"`
+	Type        string      `json:"type"`
+	Scope       string      `json:"scope"`
+	Description string      `json:"description"`
+	Title       string      `json:"title"`
+	Body        string      `json:"body"`
+	Breaking    bool        `json:"breaking"`
+	Author      Contributor `json:"author"`
+	// CoAuthors are the people credited with Co-authored-by trailers.
+	CoAuthors []Contributor `json:"coAuthors"`
+	Committer Contributor   `json:"committer"`
+	// Date is when the commit was authored.
+	Date time.Time `json:"date"`
+	// References are the issues and pull requests the commit references.
+	References []Reference `json:"references"`
+}
+
+// BreakingChange is a breaking change and the commit introducing it.
+type BreakingChange struct {
+	Description string     `json:"description"`
+	Commit      NoteCommit `json:"commit"`
+}
+
+// Contributor is a commit author, co-author or committer.
+type Contributor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Commits is the number of commits of the release the contributor
+	// authored or co-authored, only set in ReleaseNotes.Contributors.
+	Commits int `json:"commits,omitempty"`
+}
+
+// botPattern matches the names and emails of bots, such as
+// "dependabot[bot]" or "renovate-bot".
+var botPattern = regexp.MustCompile(`(?i)(\[bot\]|[-_.]bot\b|^(dependabot|renovate|github-actions|greenkeeper|snyk)\b)`)
+
+// key identifies the contributor, names and emails being already mapped
+// through the .mailmap by git.
+func (c Contributor) key() string {
+	if c.Email != "" {
+		return strings.ToLower(c.Email)
+	}
+	return c.Name
+}
+
+// IsBot reports whether a contributor looks like a bot.
+func (c Contributor) IsBot() bool {
+	local, _, _ := strings.Cut(c.Email, "@")
+	return botPattern.MatchString(c.Name) || botPattern.MatchString(local)
+}
+
+// Notes builds the release notes of the next version, as computed by Next,
+// from the commits since the previous stable tag.
+func Notes(ctx context.Context, opts Options) (*ReleaseNotes, error) {
+	result, err := Next(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := commitsBetween(ctx, result.PreviousTag, opts.ref(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := newNotes(ctx, opts, commits)
+	if err != nil {
+		return nil, err
+	}
+	notes.Version = result.Version.String()
+	notes.Tag = result.Tag
+	if result.PreviousTag != "" {
+		notes.PreviousVersion = result.Previous.String()
+		notes.PreviousTag = result.PreviousTag
+	}
+	notes.Date = time.Now()
+	return notes, nil
+}
+
+// TagNotes builds the release notes of an existing tag, from the commits
+// since the previous stable tag, e.g. to publish a release once tagged.
+func TagNotes(ctx context.Context, opts Options, tag string) (*ReleaseNotes, error) {
+	if err := prepare(ctx, opts, func() (bool, error) {
+		previous, err := git.PreviousStableTag(ctx, tag, opts.Prefix, opts.Pattern)
+		return previous != "", err
+	}); err != nil {
+		return nil, err
+	}
+
+	version, err := versionFromTag(tag, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	previousTag, err := git.PreviousStableTag(ctx, tag, opts.Prefix, opts.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous stable tag: %w", err)
+	}
+
+	commits, err := commitsBetween(ctx, previousTag, "tags/"+tag, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := newNotes(ctx, opts, commits)
+	if err != nil {
+		return nil, err
+	}
+	notes.Version = version.String()
+	notes.Tag = tag
+	if previousTag != "" {
+		previous, err := versionFromTag(previousTag, opts.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		notes.PreviousVersion = previous.String()
+		notes.PreviousTag = previousTag
+	}
+	notes.Date = time.Now()
+	return notes, nil
+}
+
+func newNotes(ctx context.Context, opts Options, commits []git.Commit) (*ReleaseNotes, error) {
+	links, err := newLinker(ctx, opts.Links)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := buildNotes(commits, links)
+	if opts.ExcludeBots {
+		notes.Contributors = slices.DeleteFunc(notes.Contributors, Contributor.IsBot)
+	}
+	return notes, nil
+}
+
+func buildNotes(commits []git.Commit, links *linker) *ReleaseNotes {
+	notes := &ReleaseNotes{}
+	groups := map[string]*CommitGroup{}
+	contributors := map[string]int{}
+
+	for _, commit := range commits {
+		parsed := parseCommit(commit)
+		description, refs := links.references(commit, parsed)
+		note := NoteCommit{
+			SHA:         commit.SHA,
+			ShortSHA:    shortSHA(commit.SHA),
+			Type:        parsed.Type,
+			Scope:       parsed.Scope,
+			Description: description,
+			Title:       commit.Title,
+			Body:        commit.Body,
+			Breaking:    isBreaking(commit),
+			Author:      Contributor{Name: commit.AuthorName, Email: commit.AuthorEmail},
+			Committer:   Contributor{Name: commit.CommitterName, Email: commit.CommitterEmail},
+			Date:        commit.AuthorDate,
+			References:  refs,
+		}
+		for _, p := range commit.CoAuthors {
+			note.CoAuthors = append(note.CoAuthors, Contributor{Name: p.Name, Email: p.Email})
+		}
+		notes.Commits = append(notes.Commits, note)
+
+		for _, ref := range refs {
+			if ref.Closed && !slices.ContainsFunc(notes.ClosedIssues, func(r Reference) bool { return r.Kind == ref.Kind && r.ID == ref.ID }) {
+				notes.ClosedIssues = append(notes.ClosedIssues, ref)
+			}
+		}
+
+		group, ok := groups[note.Type]
+		if !ok {
+			group = &CommitGroup{Type: note.Type, Title: typeTitle(note.Type)}
+			groups[note.Type] = group
+		}
+		group.Commits = append(group.Commits, note)
+
+		if note.Breaking {
+			notes.Breaking = append(notes.Breaking, breakingChanges(parsed, note)...)
+		}
+
+		counted := map[string]bool{}
+		for _, c := range append([]Contributor{note.Author}, note.CoAuthors...) {
+			key := c.key()
+			if key == "" || counted[key] {
+				continue
+			}
+			counted[key] = true
+			idx, ok := contributors[key]
+			if !ok {
+				idx = len(notes.Contributors)
+				contributors[key] = idx
+				notes.Contributors = append(notes.Contributors, c)
+			}
+			notes.Contributors[idx].Commits++
+		}
+	}
+
+	for _, group := range groups {
+		group.Scopes = groupByScope(group.Commits)
+		notes.Groups = append(notes.Groups, *group)
+	}
+	slices.SortFunc(notes.Groups, func(a, b CommitGroup) int {
+		if c := cmp.Compare(typeOrder(a.Type), typeOrder(b.Type)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	sort.SliceStable(notes.Contributors, func(i, j int) bool {
+		return strings.ToLower(notes.Contributors[i].Name) < strings.ToLower(notes.Contributors[j].Name)
+	})
+	return notes
+}
+
+// breakingChanges returns the BREAKING CHANGE footers of a commit, or its
+// description when it is only marked with "!".
+func breakingChanges(parsed conventionalCommit, note NoteCommit) []BreakingChange {
+	var changes []BreakingChange
+	for _, f := range parsed.Footers {
+		if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+			changes = append(changes, BreakingChange{Description: f.Value, Commit: note})
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, BreakingChange{Description: note.Description, Commit: note})
+	}
+	return changes
+}
+
+func groupByScope(commits []NoteCommit) []ScopeGroup {
+	var scopes []ScopeGroup
+	for _, commit := range commits {
+		idx := slices.IndexFunc(scopes, func(s ScopeGroup) bool { return s.Scope == commit.Scope })
+		if idx < 0 {
+			scopes = append(scopes, ScopeGroup{Scope: commit.Scope})
+			idx = len(scopes) - 1
+		}
+		scopes[idx].Commits = append(scopes[idx].Commits, commit)
+	}
+	sort.SliceStable(scopes, func(i, j int) bool { return scopes[i].Scope < scopes[j].Scope })
+	return scopes
+}
+
+func typeOrder(commitType string) int {
+	for i, t := range commitTypes {
+		if t.Type == commitType {
+			return i
+		}
+	}
+	if commitType == "" {
+		return len(commitTypes) + 1
+	}
+	return len(commitTypes)
+}
+
+func typeTitle(commitType string) string {
+	for _, t := range commitTypes {
+		if t.Type == commitType {
+			return t.Title
+		}
+	}
+	if commitType == "" {
+		return "Other Changes"
+	}
+	return strings.ToUpper(commitType[:1]) + commitType[1:]
+}
+
+// ParseTemplate parses a release notes template. On top of the text/template
+// builtins, templates can use "emoji", returning the emoji of a commit type.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"emoji": func(commitType string) string {
+			if emoji, ok := typeEmojis[commitType]; ok {
+				return emoji
+			}
+			return "🔹"
+		},
+	}).Parse(text)
+}
+
+// BuiltinTemplate returns one of the BuiltinTemplates.
+func BuiltinTemplate(name string) (*template.Template, error) {
+	text, err := builtinTemplates.ReadFile("templates/" + name + ".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("unknown builtin template '%s', expected one of %s", name, strings.Join(BuiltinTemplates, ", "))
+	}
+	return ParseTemplate(name, string(text))
+}
+
+// Render executes a release notes template.
+func (n *ReleaseNotes) Render(w io.Writer, tmpl *template.Template) error {
+	if err := tmpl.Execute(w, n); err != nil {
+		return fmt.Errorf("failed to render release notes: %w", err)
+	}
+	return nil
+}
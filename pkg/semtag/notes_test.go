@@ -0,0 +1,225 @@
+package semtag
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildNotes(t *testing.T) {
+	notes := buildNotes([]git.Commit{
+		{SHA: "1111111111", Title: "feat(cli): add foo", AuthorName: "Alice", AuthorEmail: "alice@example.com"},
+		{SHA: "2222222222", Title: "fix: bar", AuthorName: "bob", AuthorEmail: "bob@example.com"},
+		{SHA: "3333333333", Title: "refactor!: drop baz", Body: "BREAKING CHANGE: baz is gone\n\nRefs: #1", AuthorName: "Alice", AuthorEmail: "ALICE@example.com"},
+		{SHA: "4444444444", Title: "feat: qux", AuthorName: "Carol", AuthorEmail: "carol@example.com"},
+		{SHA: "5555555555", Title: "update readme", AuthorName: "Carol", AuthorEmail: "carol@example.com"},
+		{SHA: "6666666666", Title: "fix!: quux", AuthorName: "Carol", AuthorEmail: "carol@example.com"},
+	}, &linker{})
+
+	var types []string
+	for _, group := range notes.Groups {
+		types = append(types, group.Type)
+	}
+	require.Equal(t, []string{"feat", "fix", "refactor", ""}, types)
+	require.Equal(t, "Other Changes", notes.Groups[3].Title)
+
+	features := notes.Groups[0]
+	require.Equal(t, "Features", features.Title)
+	require.Len(t, features.Commits, 2)
+	require.Equal(t, []ScopeGroup{
+		{Scope: "", Commits: []NoteCommit{features.Commits[1]}},
+		{Scope: "cli", Commits: []NoteCommit{features.Commits[0]}},
+	}, features.Scopes)
+
+	require.Len(t, notes.Breaking, 2)
+	require.Equal(t, "baz is gone", notes.Breaking[0].Description)
+	require.Equal(t, "3333333", notes.Breaking[0].Commit.ShortSHA)
+	require.Equal(t, "quux", notes.Breaking[1].Description)
+
+	require.Equal(t, []Contributor{
+		{Name: "Alice", Email: "alice@example.com", Commits: 2},
+		{Name: "bob", Email: "bob@example.com", Commits: 1},
+		{Name: "Carol", Email: "carol@example.com", Commits: 3},
+	}, notes.Contributors)
+	require.Len(t, notes.Commits, 6)
+}
+
+func TestNotesContributors(t *testing.T) {
+	notes := buildNotes([]git.Commit{
+		{SHA: "1", Title: "feat: foo", AuthorName: "Alice", AuthorEmail: "alice@example.com", CoAuthors: []git.Person{
+			{Name: "Bob", Email: "bob@example.com"},
+			{Name: "Alice", Email: "Alice@example.com"},
+		}},
+		{SHA: "2", Title: "chore(deps): bump", AuthorName: "dependabot[bot]", AuthorEmail: "49699333+dependabot[bot]@users.noreply.github.com"},
+		{SHA: "3", Title: "fix: bar", AuthorName: "Bob", AuthorEmail: "bob@example.com"},
+	}, &linker{})
+
+	require.Equal(t, []Contributor{
+		{Name: "Alice", Email: "alice@example.com", Commits: 1},
+		{Name: "Bob", Email: "bob@example.com", Commits: 2},
+		{Name: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com", Commits: 1},
+	}, notes.Contributors)
+	require.Equal(t, []Contributor{{Name: "Bob", Email: "bob@example.com"}, {Name: "Alice", Email: "Alice@example.com"}}, notes.Commits[0].CoAuthors)
+
+	for contributor, bot := range map[Contributor]bool{
+		{Name: "dependabot[bot]"}:                             true,
+		{Name: "Renovate Bot", Email: "bot@renovateapp.com"}:  true,
+		{Name: "ci", Email: "release-bot@example.com"}:        true,
+		{Name: "github-actions", Email: "actions@github.com"}: true,
+		{Name: "Abbot", Email: "abbot@example.com"}:           false,
+		{Name: "Alice", Email: "alice@example.com"}:           false,
+	} {
+		require.Equal(t, bot, contributor.IsBot(), contributor.Name)
+	}
+}
+
+func TestRenderNotes(t *testing.T) {
+	notes := buildNotes([]git.Commit{
+		{SHA: "1111111111", Title: "feat(cli): add foo", AuthorName: "Alice", AuthorEmail: "alice@example.com"},
+		{SHA: "2222222222", Title: "fix: bar", AuthorName: "Bob", AuthorEmail: "bob@example.com"},
+	}, &linker{})
+	notes.Tag = "v1.3.0"
+	notes.PreviousTag = "v1.2.0"
+	notes.Date = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("markdown", func(t *testing.T) {
+		tmpl, err := BuiltinTemplate("markdown")
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, notes.Render(&out, tmpl))
+		require.Equal(t, `## v1.3.0 (2024-03-01)
+
+### ✨ Features
+
+- **cli:** add foo (1111111)
+
+### 🐛 Bug Fixes
+
+- bar (2222222)
+
+### Contributors
+
+- Alice
+- Bob
+`, out.String())
+	})
+
+	t.Run("references", func(t *testing.T) {
+		links, err := compileLinks(LinkOptions{}, "https://github.com/foo/bar")
+		require.NoError(t, err)
+		notes := buildNotes([]git.Commit{
+			{SHA: "3333333333", Title: "fix: baz (#12)", Body: "Fixes #10", AuthorName: "Bob", AuthorEmail: "bob@example.com"},
+		}, links)
+		notes.Tag = "v1.3.1"
+		notes.Date = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+		tmpl, err := BuiltinTemplate("markdown")
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, notes.Render(&out, tmpl))
+		require.Equal(t, `## v1.3.1 (2024-03-01)
+
+### 🐛 Bug Fixes
+
+- baz (3333333, [#12](https://github.com/foo/bar/pull/12), [#10](https://github.com/foo/bar/issues/10))
+
+### Issues Closed
+
+- [#10](https://github.com/foo/bar/issues/10)
+
+### Contributors
+
+- Bob
+`, out.String())
+	})
+
+	t.Run("all builtins", func(t *testing.T) {
+		for _, name := range BuiltinTemplates {
+			tmpl, err := BuiltinTemplate(name)
+			require.NoError(t, err)
+			var out bytes.Buffer
+			require.NoError(t, notes.Render(&out, tmpl))
+			require.Contains(t, out.String(), "v1.3.0")
+			require.Contains(t, out.String(), "add foo")
+		}
+	})
+
+	t.Run("html escaping", func(t *testing.T) {
+		notes := buildNotes([]git.Commit{
+			{SHA: "1111111111", Title: "feat(<b>ui</b>): add <script>", Body: "BREAKING CHANGE: drop <i>", AuthorName: "<Eve>", AuthorEmail: "eve@example.com"},
+		}, &linker{})
+		notes.Tag = `<img src=x onerror="alert(1)">v1.0.0`
+
+		tmpl, err := BuiltinTemplate("html")
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, notes.Render(&out, tmpl))
+		require.Contains(t, out.String(), "<h2>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;v1.0.0 <small>")
+		require.Contains(t, out.String(), "<strong>&lt;b&gt;ui&lt;/b&gt;:</strong> add &lt;script&gt; (<code>1111111</code>)")
+		require.Contains(t, out.String(), "drop &lt;i&gt;")
+		require.Contains(t, out.String(), "<li>&lt;Eve&gt;</li>")
+		require.NotContains(t, out.String(), "<script>")
+		require.NotContains(t, out.String(), "<img")
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		tmpl, err := ParseTemplate("custom", `{{ .PreviousTag }}..{{ .Tag }}{{ range .Commits }} {{ emoji .Type }}{{ end }}`)
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, notes.Render(&out, tmpl))
+		require.Equal(t, "v1.2.0..v1.3.0 ✨ 🐛", out.String())
+	})
+
+	t.Run("unknown builtin", func(t *testing.T) {
+		_, err := BuiltinTemplate("nope")
+		require.Error(t, err)
+	})
+}
+
+func TestNotes(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: foobar")
+	gitTag(t, "v1.2.3")
+	gitCommit(t, "fix: foo")
+	gitCommit(t, "feat: bar")
+
+	notes, err := Notes(context.Background(), Options{Prefix: "v", Branch: "main"})
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", notes.Version)
+	require.Equal(t, "v1.3.0", notes.Tag)
+	require.Equal(t, "1.2.3", notes.PreviousVersion)
+	require.Equal(t, "v1.2.3", notes.PreviousTag)
+	require.Len(t, notes.Commits, 2)
+	require.Equal(t, []Contributor{{Name: "svu", Email: "svu@example.com", Commits: 2}}, notes.Contributors)
+}
+
+func TestTagNotes(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: foobar")
+	gitTag(t, "v1.2.3")
+	gitCommit(t, "fix: foo")
+	gitTag(t, "v1.2.4-rc.1")
+	gitCommit(t, "feat: bar")
+	gitTag(t, "v1.3.0")
+	gitCommit(t, "fix: after the release")
+
+	notes, err := TagNotes(context.Background(), Options{Prefix: "v"}, "v1.3.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", notes.Version)
+	require.Equal(t, "v1.3.0", notes.Tag)
+	require.Equal(t, "1.2.3", notes.PreviousVersion)
+	require.Equal(t, "v1.2.3", notes.PreviousTag)
+	require.Len(t, notes.Commits, 2)
+	require.Equal(t, "bar", notes.Commits[0].Description)
+
+	t.Run("first release", func(t *testing.T) {
+		notes, err := TagNotes(context.Background(), Options{Prefix: "v"}, "v1.2.3")
+		require.NoError(t, err)
+		require.Empty(t, notes.PreviousTag)
+		require.Len(t, notes.Commits, 1)
+	})
+}
@@ -0,0 +1,103 @@
+package semtag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// approvalToken is the commit footer approving a major bump beyond
+// Policy.MaxBump.
+const approvalToken = "Approved-Breaking-Change"
+
+// approvalLine matches the lines of commit messages which may be an
+// Approved-Breaking-Change footer.
+var approvalLine = regexp.MustCompile(`(?im)^` + approvalToken + `(: | #)`)
+
+// Policy restricts the versions Next returns, making it a release gate rather
+// than just a calculator. The zero value allows everything.
+type Policy struct {
+	// MaxBump is the highest bump allowed, BumpNone meaning no limit. A major
+	// bump beyond it is still allowed with AllowMajor, or when one of the
+	// commits since the latest release has an Approved-Breaking-Change
+	// footer.
+	MaxBump Bump
+	// AllowMajor approves a major bump beyond MaxBump.
+	AllowMajor bool
+	// MinBreakingDescription is the minimum length of the BREAKING CHANGE
+	// footer every breaking change must be described with, zero meaning
+	// none is required.
+	MinBreakingDescription int
+}
+
+// PolicyError is returned when the next version doesn't follow
+// Options.Policy.
+type PolicyError struct {
+	// Bump is the increment of the refused version.
+	Bump Bump
+	// Commit is the commit breaking the policy, nil when the bump was forced
+	// through Options.
+	Commit *Commit
+	// Reason explains what the policy requires.
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	if e.Commit == nil {
+		return fmt.Sprintf("%s bump refused: %s", e.Bump, e.Reason)
+	}
+	return fmt.Sprintf("%s bump refused, %s %s: %s", e.Bump, shortSHA(e.Commit.SHA), e.Commit.Title, e.Reason)
+}
+
+// readsHistory reports whether enforcing the policy may need all the commits
+// since the latest release, which decide then reads for it.
+func (p Policy) readsHistory() bool {
+	return p.MinBreakingDescription > 0 || (p.MaxBump != BumpNone && p.MaxBump < BumpMajor && !p.AllowMajor)
+}
+
+// enforce checks the decided version and the commits it was decided from
+// follow the policy.
+func (p Policy) enforce(decided decision) error {
+	if p.MinBreakingDescription > 0 && decided.bump == BumpMajor {
+		for _, commit := range decided.classified.breaking {
+			if len(breakingDescription(commit)) < p.MinBreakingDescription {
+				return &PolicyError{
+					Bump:   decided.bump,
+					Commit: &commit,
+					Reason: fmt.Sprintf("breaking changes need a BREAKING CHANGE footer of at least %d characters describing them", p.MinBreakingDescription),
+				}
+			}
+		}
+	}
+
+	if p.MaxBump == BumpNone || decided.bump <= p.MaxBump {
+		return nil
+	}
+	if decided.bump == BumpMajor {
+		if p.AllowMajor || decided.classified.approved {
+			return nil
+		}
+		return &PolicyError{
+			Bump:   decided.bump,
+			Commit: decided.commit,
+			Reason: fmt.Sprintf("the maximum bump is %s, unless approved by an %s footer", p.MaxBump, approvalToken),
+		}
+	}
+	return &PolicyError{
+		Bump:   decided.bump,
+		Commit: decided.commit,
+		Reason: fmt.Sprintf("the maximum bump is %s", p.MaxBump),
+	}
+}
+
+// breakingDescription returns the value of the BREAKING CHANGE footer of a
+// commit, empty when it has none.
+func breakingDescription(commit Commit) string {
+	parsed := parseCommit(commit)
+	for _, token := range []string{"BREAKING CHANGE", "BREAKING-CHANGE"} {
+		if value, ok := parsed.footer(token); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package semtag
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextPolicy(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "chore: foobar")
+		gitTag(tb, "v1.2.3")
+		gitCommit(tb, "fix: foo")
+	}
+	next := func(policy Policy) (*Result, error) {
+		return Next(context.Background(), Options{Prefix: "v", Branch: "main", Policy: policy})
+	}
+
+	t.Run("within the maximum bump", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat: bar")
+		result, err := next(Policy{MaxBump: BumpMinor})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0", result.Tag)
+	})
+
+	t.Run("beyond the maximum bump", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat: bar")
+		_, err := next(Policy{MaxBump: BumpPatch})
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, BumpMinor, policyErr.Bump)
+		require.Equal(t, "feat: bar", policyErr.Commit.Title)
+		require.Regexp(t, `^minor bump refused, [0-9a-f]{7} feat: bar: the maximum bump is patch$`, err.Error())
+
+		// AllowMajor only approves major bumps.
+		_, err = next(Policy{MaxBump: BumpPatch, AllowMajor: true})
+		require.ErrorAs(t, err, &policyErr)
+	})
+
+	t.Run("unapproved breaking change", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat!: bar")
+		_, err := next(Policy{MaxBump: BumpMinor})
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, BumpMajor, policyErr.Bump)
+		require.ErrorContains(t, err, "the maximum bump is minor, unless approved by an Approved-Breaking-Change footer")
+
+		result, err := next(Policy{MaxBump: BumpMinor, AllowMajor: true})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+	})
+
+	t.Run("approved breaking change", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat!: bar")
+		gitCommit(t, "chore: approve\n\nApproved-Breaking-Change: @maintainer")
+		result, err := next(Policy{MaxBump: BumpMinor})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+	})
+
+	t.Run("forced bump", func(t *testing.T) {
+		setup(t)
+		_, err := Next(context.Background(), Options{Prefix: "v", Branch: "main", Bump: BumpMajor, Policy: Policy{MaxBump: BumpMinor}})
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Nil(t, policyErr.Commit)
+		require.EqualError(t, err, "major bump refused: the maximum bump is minor, unless approved by an Approved-Breaking-Change footer")
+	})
+
+	t.Run("breaking change description", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat(api): bar\n\nBREAKING CHANGE: Client.Do takes a context, pass context.Background() to keep the previous behavior.")
+		gitCommit(t, "feat!: baz\n\nBREAKING-CHANGE: renamed")
+
+		_, err := next(Policy{MinBreakingDescription: 20})
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "feat!: baz", policyErr.Commit.Title)
+		require.ErrorContains(t, err, "breaking changes need a BREAKING CHANGE footer of at least 20 characters describing them")
+
+		result, err := next(Policy{MinBreakingDescription: 7})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+	})
+}
+
+// countingSource counts how many times the commits are read.
+type countingSource struct {
+	commitList
+	reads int
+}
+
+func (s *countingSource) commits() iter.Seq2[git.Commit, error] {
+	s.reads++
+	return s.commitList.commits()
+}
+
+func TestEnforceReadsCommitsOnce(t *testing.T) {
+	current := semver.MustParse("1.2.3")
+	// The approval and the undescribed breaking change are older than the
+	// first breaking change, where classifying alone would stop.
+	commits := commitList{
+		{SHA: "aaa", Title: "feat!: bar", Body: "BREAKING CHANGE: Client.Do takes a context"},
+		{SHA: "bbb", Title: "chore: approve", Body: "Approved-Breaking-Change: @maintainer"},
+		{SHA: "ccc", Title: "fix!: baz"},
+	}
+
+	for name, tt := range map[string]struct {
+		opts Options
+		err  string
+	}{
+		"approved":  {opts: Options{Policy: Policy{MaxBump: BumpMinor}}},
+		"forced":    {opts: Options{Bump: BumpMajor, Policy: Policy{MaxBump: BumpMinor}}},
+		"described": {opts: Options{Policy: Policy{MinBreakingDescription: 10}}, err: "major bump refused, ccc fix!: baz: breaking changes need a BREAKING CHANGE footer of at least 10 characters describing them"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			source := &countingSource{commitList: commits}
+			decided, err := decide(current, source, tt.opts)
+			require.NoError(t, err)
+			err = tt.opts.Policy.enforce(decided)
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.err)
+			}
+			require.Equal(t, 1, source.reads)
+		})
+	}
+}
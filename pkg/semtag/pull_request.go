@@ -0,0 +1,96 @@
+package semtag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// pullRequestIdentifier starts the pre-release of pull request preview
+// versions. No branch maps to it, so previews never collide with real
+// pre-release tags.
+const pullRequestIdentifier = "pr"
+
+// PullRequestOptions configures pull request preview versions.
+type PullRequestOptions struct {
+	// Enabled replaces the branch pre-release suffix with a pull request
+	// one, e.g. 1.5.0-pr.482.3.
+	Enabled bool
+	// Number and Base override what is detected from the CI environment.
+	Number int
+	Base   string
+}
+
+// resolvePullRequest combines the explicit options with what can be detected
+// from the CI environment and the refs pointing at HEAD.
+func resolvePullRequest(ctx context.Context, opts PullRequestOptions) (*git.PullRequest, error) {
+	pr := git.PullRequestFromCI()
+	if pr == nil {
+		pr = git.PullRequestFromRefs(ctx)
+	}
+	if pr == nil {
+		pr = &git.PullRequest{}
+	}
+
+	if opts.Number > 0 {
+		pr.Number = opts.Number
+	}
+	if opts.Base != "" {
+		pr.Base = opts.Base
+	}
+	if pr.Base == "" {
+		pr.Base = git.DefaultBranch(ctx)
+	}
+
+	if pr.Number == 0 {
+		return nil, &OptionError{Field: "PullRequest.Number", Err: errors.New("could not detect the pull request number")}
+	}
+	if pr.Base == "" {
+		return nil, &OptionError{Field: "PullRequest.Base", Err: errors.New("could not detect the pull request base branch")}
+	}
+	return pr, nil
+}
+
+// applyPullRequestSuffix sets a pr.<number>.<count> pre-release, where count is
+// the number of commits in the pull request, whose head is rev, since it
+// diverged from its base. It only depends on the history, so no tag is needed
+// to track it.
+func applyPullRequestSuffix(ctx context.Context, version semver.Version, rev string, opts PullRequestOptions) (semver.Version, error) {
+	pr, err := resolvePullRequest(ctx, opts)
+	if err != nil {
+		return version, err
+	}
+
+	base, err := git.ResolveBranch(ctx, pr.Base)
+	if err != nil {
+		return version, fmt.Errorf("failed to resolve pull request base: %w", err)
+	}
+
+	head := rev
+	// The second parent of a merge ref is the head of the pull request,
+	// unless the head itself was checked out, as workflows checking out
+	// github.event.pull_request.head.sha do.
+	if pr.MergeRef && rev == "HEAD" && git.CommitExists(ctx, "HEAD^2") {
+		head = "HEAD^2"
+	}
+
+	mergeBase, err := git.MergeBase(ctx, base, head)
+	if err != nil {
+		return version, fmt.Errorf("failed to find merge base with '%s': %w", pr.Base, err)
+	}
+
+	count, err := git.CountCommits(ctx, mergeBase, head)
+	if err != nil {
+		return version, fmt.Errorf("failed to count pull request commits: %w", err)
+	}
+
+	suffix := fmt.Sprintf("%s.%d.%d", pullRequestIdentifier, pr.Number, count)
+	withSuffix, err := version.SetPrerelease(suffix)
+	if err != nil {
+		return version, fmt.Errorf("failed to set prerelease suffix '%s': %w", suffix, err)
+	}
+	return withSuffix, nil
+}
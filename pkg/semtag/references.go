@@ -0,0 +1,240 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kinds of references.
+const (
+	ReferenceIssue       = "issue"
+	ReferencePullRequest = "pull-request"
+	// ReferenceTracker is a key of an external issue tracker, see Tracker.
+	ReferenceTracker = "tracker"
+)
+
+// closingKeywords are the footer tokens closing the issues they reference, as
+// understood by GitHub, GitLab and Gitea.
+var closingKeywords = []string{"close", "closes", "closed", "fix", "fixes", "fixed", "resolve", "resolves", "resolved"}
+
+var (
+	// trailingPullRequest is the "(#123)" GitHub adds to squash merge titles.
+	trailingPullRequest = regexp.MustCompile(`\s*\(#(\d+)\)$`)
+	mergePullRequest    = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+	// gitlabMergeRequest is the line GitLab adds to merge commits.
+	gitlabMergeRequest = regexp.MustCompile(`(?m)^See merge request \S*!(\d+)$`)
+	issueReference     = regexp.MustCompile(`(?:^|[\s(,])#(\d+)\b`)
+)
+
+// LinkOptions configures how references to issues and pull requests found in
+// commits are linked in release notes and changelogs.
+type LinkOptions struct {
+	// IssueURL and PullRequestURL are the templates of the links, in which
+	// {id} is replaced with the number, e.g.
+	// https://github.com/owner/repo/issues/{id}. They are derived from
+	// RepositoryURL when empty.
+	IssueURL       string
+	PullRequestURL string
+	// RepositoryURL is the web URL of the repository, derived from the origin
+	// remote when empty.
+	RepositoryURL string
+	// Trackers are the external issue trackers whose keys are recognised.
+	Trackers []Tracker
+}
+
+// Tracker is an external issue tracker, such as Jira.
+type Tracker struct {
+	// Pattern is a regular expression matching the keys of the tracker, e.g.
+	// `[A-Z][A-Z0-9]+-\d+` for Jira.
+	Pattern string
+	// URL is the template of the links, in which {id} is replaced with the
+	// key, e.g. https://example.atlassian.net/browse/{id}.
+	URL string
+}
+
+// Reference is an issue, pull request or tracker key referenced by a commit.
+type Reference struct {
+	Kind string `json:"kind"`
+	// ID is the number of issues and pull requests, or the tracker key.
+	ID string `json:"id"`
+	// Text is how the reference is written, e.g. "#123", "!123" for GitLab
+	// merge requests, or "ABC-42".
+	Text string `json:"text"`
+	// URL is empty when no link template is configured.
+	URL string `json:"url,omitempty"`
+	// Closed is true when the commit closes the issue, e.g. "Fixes: #123".
+	Closed bool `json:"closed,omitempty"`
+}
+
+// linker finds references in commits and turns them into links.
+type linker struct {
+	issueURL       string
+	pullRequestURL string
+	// mergeRequests is set for GitLab, whose merge requests are written !123.
+	mergeRequests bool
+	trackers      []tracker
+}
+
+type tracker struct {
+	pattern *regexp.Regexp
+	url     string
+}
+
+// newLinker compiles the link options, deriving the link templates from the
+// repository when needed.
+func newLinker(ctx context.Context, opts LinkOptions) (*linker, error) {
+	repositoryURL := opts.RepositoryURL
+	if repositoryURL == "" && (opts.IssueURL == "" || opts.PullRequestURL == "") {
+		repositoryURL, _ = RepositoryURL(ctx)
+	}
+	return compileLinks(opts, repositoryURL)
+}
+
+func compileLinks(opts LinkOptions, repositoryURL string) (*linker, error) {
+	l := &linker{issueURL: opts.IssueURL, pullRequestURL: opts.PullRequestURL}
+
+	if repositoryURL != "" {
+		repositoryURL = strings.TrimSuffix(repositoryURL, "/")
+		issues, pullRequests := "/issues/{id}", "/pull/{id}"
+		switch {
+		case strings.Contains(repositoryURL, "gitlab"):
+			issues, pullRequests = "/-/issues/{id}", "/-/merge_requests/{id}"
+			l.mergeRequests = true
+		case strings.Contains(repositoryURL, "gitea"), strings.Contains(repositoryURL, "forgejo"), strings.Contains(repositoryURL, "codeberg.org"):
+			pullRequests = "/pulls/{id}"
+		}
+		if l.issueURL == "" {
+			l.issueURL = repositoryURL + issues
+		}
+		if l.pullRequestURL == "" {
+			l.pullRequestURL = repositoryURL + pullRequests
+		}
+	}
+
+	for _, t := range opts.Trackers {
+		pattern, err := regexp.Compile(`\b(?:` + t.Pattern + `)\b`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tracker pattern '%s': %w", t.Pattern, err)
+		}
+		l.trackers = append(l.trackers, tracker{pattern: pattern, url: t.URL})
+	}
+	return l, nil
+}
+
+// references returns the references of a commit, and its description without
+// the pull request number GitHub appends to squash merges.
+func (l *linker) references(commit Commit, parsed conventionalCommit) (string, []Reference) {
+	var refs []Reference
+	add := func(ref Reference) {
+		// Merge commit titles mention the pull request as "#123" too.
+		if ref.Kind == ReferenceIssue && !l.mergeRequests && slices.ContainsFunc(refs, func(r Reference) bool {
+			return r.Kind == ReferencePullRequest && r.ID == ref.ID
+		}) {
+			return
+		}
+		idx := slices.IndexFunc(refs, func(r Reference) bool { return r.Kind == ref.Kind && r.ID == ref.ID })
+		switch {
+		case idx < 0:
+			refs = append(refs, ref)
+		case ref.Closed:
+			refs[idx].Closed = true
+		}
+	}
+
+	description := parsed.Description
+	if m := trailingPullRequest.FindStringSubmatch(description); m != nil {
+		description = strings.TrimSpace(strings.TrimSuffix(description, m[0]))
+		add(l.pullRequest(m[1]))
+	}
+	if m := mergePullRequest.FindStringSubmatch(commit.Title); m != nil {
+		add(l.pullRequest(m[1]))
+	}
+	if m := gitlabMergeRequest.FindStringSubmatch(commit.Body); m != nil {
+		add(l.pullRequest(m[1]))
+	}
+
+	for _, ref := range l.find(description) {
+		add(ref)
+	}
+	for _, f := range parsed.Footers {
+		closes := slices.Contains(closingKeywords, strings.ToLower(f.Token))
+		value := f.Value
+		// "Fixes #123" is parsed as a footer whose value lost its "#".
+		if value != "" && isDigit(value[0]) {
+			value = "#" + value
+		}
+		for _, ref := range l.find(value) {
+			ref.Closed = closes && ref.Kind != ReferencePullRequest
+			add(ref)
+		}
+	}
+	return description, refs
+}
+
+// find returns the issue and tracker references in text.
+func (l *linker) find(text string) []Reference {
+	var refs []Reference
+	for _, m := range issueReference.FindAllStringSubmatch(text, -1) {
+		refs = append(refs, Reference{
+			Kind: ReferenceIssue,
+			ID:   m[1],
+			Text: "#" + m[1],
+			URL:  expandLink(l.issueURL, m[1]),
+		})
+	}
+	for _, t := range l.trackers {
+		for _, key := range t.pattern.FindAllString(text, -1) {
+			refs = append(refs, Reference{
+				Kind: ReferenceTracker,
+				ID:   key,
+				Text: key,
+				URL:  expandLink(t.url, key),
+			})
+		}
+	}
+	return refs
+}
+
+func (l *linker) pullRequest(id string) Reference {
+	text := "#" + id
+	if l.mergeRequests {
+		text = "!" + id
+	}
+	return Reference{
+		Kind: ReferencePullRequest,
+		ID:   id,
+		Text: text,
+		URL:  expandLink(l.pullRequestURL, id),
+	}
+}
+
+func expandLink(template, id string) string {
+	if template == "" {
+		return ""
+	}
+	return strings.ReplaceAll(template, "{id}", id)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// markdownReferences renders references as markdown links, e.g.
+// " ([#12](https://...), ABC-42)", or an empty string when there are none.
+func markdownReferences(refs []Reference) string {
+	if len(refs) == 0 {
+		return ""
+	}
+	var links []string
+	for _, ref := range refs {
+		if ref.URL == "" {
+			links = append(links, ref.Text)
+		} else {
+			links = append(links, "["+ref.Text+"]("+ref.URL+")")
+		}
+	}
+	return " (" + strings.Join(links, ", ") + ")"
+}
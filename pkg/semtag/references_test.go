@@ -0,0 +1,83 @@
+package semtag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+	links, err := compileLinks(LinkOptions{
+		Trackers: []Tracker{{Pattern: `[A-Z][A-Z0-9]+-\d+`, URL: "https://example.atlassian.net/browse/{id}"}},
+	}, "https://github.com/foo/bar")
+	require.NoError(t, err)
+
+	for name, tt := range map[string]struct {
+		commit      Commit
+		description string
+		refs        []Reference
+	}{
+		"none": {
+			commit:      Commit{Title: "fix: foo"},
+			description: "foo",
+		},
+		"squash merge": {
+			commit:      Commit{Title: "feat: foo (#123)"},
+			description: "foo",
+			refs:        []Reference{{Kind: ReferencePullRequest, ID: "123", Text: "#123", URL: "https://github.com/foo/bar/pull/123"}},
+		},
+		"merge commit": {
+			commit:      Commit{Title: "Merge pull request #7 from foo/bar", Body: "\nfeat: foo"},
+			description: "Merge pull request #7 from foo/bar",
+			refs:        []Reference{{Kind: ReferencePullRequest, ID: "7", Text: "#7", URL: "https://github.com/foo/bar/pull/7"}},
+		},
+		"closing footers": {
+			commit:      Commit{Title: "fix: foo", Body: "Some text about #4.\n\nFixes #1, #2\nRefs: ABC-42\nResolves: XYZ-1"},
+			description: "foo",
+			refs: []Reference{
+				{Kind: ReferenceIssue, ID: "1", Text: "#1", URL: "https://github.com/foo/bar/issues/1", Closed: true},
+				{Kind: ReferenceIssue, ID: "2", Text: "#2", URL: "https://github.com/foo/bar/issues/2", Closed: true},
+				{Kind: ReferenceTracker, ID: "ABC-42", Text: "ABC-42", URL: "https://example.atlassian.net/browse/ABC-42"},
+				{Kind: ReferenceTracker, ID: "XYZ-1", Text: "XYZ-1", URL: "https://example.atlassian.net/browse/XYZ-1", Closed: true},
+			},
+		},
+		"tracker key in title": {
+			commit:      Commit{Title: "fix(api): ABC-42 handle timeouts"},
+			description: "ABC-42 handle timeouts",
+			refs:        []Reference{{Kind: ReferenceTracker, ID: "ABC-42", Text: "ABC-42", URL: "https://example.atlassian.net/browse/ABC-42"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			description, refs := links.references(tt.commit, parseCommit(tt.commit))
+			require.Equal(t, tt.description, description)
+			require.Equal(t, tt.refs, refs)
+		})
+	}
+
+	t.Run("gitlab", func(t *testing.T) {
+		links, err := compileLinks(LinkOptions{}, "https://gitlab.com/group/bar")
+		require.NoError(t, err)
+		commit := Commit{Title: "feat: foo", Body: "Closes #3\n\nSee merge request group/bar!9"}
+		_, refs := links.references(commit, parseCommit(commit))
+		require.Equal(t, []Reference{
+			{Kind: ReferencePullRequest, ID: "9", Text: "!9", URL: "https://gitlab.com/group/bar/-/merge_requests/9"},
+			{Kind: ReferenceIssue, ID: "3", Text: "#3", URL: "https://gitlab.com/group/bar/-/issues/3", Closed: true},
+		}, refs)
+	})
+
+	t.Run("custom templates", func(t *testing.T) {
+		links, err := compileLinks(LinkOptions{IssueURL: "https://issues.example.com/{id}"}, "")
+		require.NoError(t, err)
+		commit := Commit{Title: "fix: foo (#5)", Body: "Fixes: #6"}
+		_, refs := links.references(commit, parseCommit(commit))
+		require.Equal(t, []Reference{
+			{Kind: ReferencePullRequest, ID: "5", Text: "#5"},
+			{Kind: ReferenceIssue, ID: "6", Text: "#6", URL: "https://issues.example.com/6", Closed: true},
+		}, refs)
+	})
+
+	t.Run("invalid tracker pattern", func(t *testing.T) {
+		_, err := compileLinks(LinkOptions{Trackers: []Tracker{{Pattern: "("}}}, "")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gobwas/glob"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// scopeFilter decides which commits are considered from their Conventional
+// Commits scopes, as set by Options.IncludeScopes and Options.ExcludeScopes.
+type scopeFilter struct {
+	include []glob.Glob
+	exclude []glob.Glob
+}
+
+func newScopeFilter(opts Options) (scopeFilter, error) {
+	var f scopeFilter
+	for _, pattern := range opts.IncludeScopes {
+		g, err := compileScope(pattern)
+		if err != nil {
+			return f, err
+		}
+		f.include = append(f.include, g)
+	}
+	for _, pattern := range opts.ExcludeScopes {
+		g, err := compileScope(pattern)
+		if err != nil {
+			return f, err
+		}
+		f.exclude = append(f.exclude, g)
+	}
+	return f, nil
+}
+
+func compileScope(pattern string) (glob.Glob, error) {
+	g, err := glob.Compile(strings.ToLower(strings.TrimSpace(pattern)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid scope pattern '%s': %w", pattern, err)
+	}
+	return g, nil
+}
+
+// excludes reports whether the commit is left out, and why. Commits with
+// several scopes, as in "feat(cli,web)", are included when any of their
+// scopes is, and excluded when all of them are.
+func (f scopeFilter) excludes(commit git.Commit) (bool, string) {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return false, ""
+	}
+
+	scopes := commitScopes(parseCommit(commit).Scope)
+	if len(f.include) > 0 {
+		scopes = slices.DeleteFunc(scopes, func(scope string) bool { return !matchesAny(f.include, scope) })
+		if len(scopes) == 0 {
+			return true, "scope not included"
+		}
+	}
+	if len(f.exclude) > 0 && len(scopes) > 0 && !slices.ContainsFunc(scopes, func(scope string) bool { return !matchesAny(f.exclude, scope) }) {
+		return true, "scope excluded"
+	}
+	return false, ""
+}
+
+// commitScopes splits a scope such as "cli,web" in its parts.
+func commitScopes(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Split(scope, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func matchesAny(globs []glob.Glob, scope string) bool {
+	return slices.ContainsFunc(globs, func(g glob.Glob) bool { return g.Match(scope) })
+}
+
+// commitsBetween returns the commits reachable from rev but not from tag (all
+// of them when tag is empty) which are considered with opts: the ones read
+// following opts.Paths and opts.MergeStrategy, and passing the scope filters.
+func commitsBetween(ctx context.Context, tag, rev string, opts Options) ([]git.Commit, error) {
+	filter, err := newScopeFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := readCommits(ctx, tag, rev, opts)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(commits, func(commit git.Commit) bool {
+		excluded, _ := filter.excludes(commit)
+		return excluded
+	}), nil
+}
@@ -0,0 +1,74 @@
+package semtag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+func TestScopeFilter(t *testing.T) {
+	for name, tt := range map[string]struct {
+		include, exclude []string
+		excluded         map[string]string
+	}{
+		"no filters": {
+			excluded: map[string]string{},
+		},
+		"include": {
+			include: []string{"cli"},
+			excluded: map[string]string{
+				"feat(web): foo": "scope not included",
+				"fix(api)!: foo": "scope not included",
+				"fix: foo":       "scope not included",
+				"update readme":  "scope not included",
+			},
+		},
+		"include glob": {
+			include: []string{"cli*", "API"},
+			excluded: map[string]string{
+				"feat(web): foo": "scope not included",
+				"fix: foo":       "scope not included",
+				"update readme":  "scope not included",
+			},
+		},
+		"exclude": {
+			exclude: []string{"web"},
+			excluded: map[string]string{
+				"feat(web): foo": "scope excluded",
+			},
+		},
+		"include and exclude": {
+			include: []string{"*"},
+			exclude: []string{"web"},
+			excluded: map[string]string{
+				"feat(web): foo": "scope excluded",
+				"fix: foo":       "scope not included",
+				"update readme":  "scope not included",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filter, err := newScopeFilter(Options{IncludeScopes: tt.include, ExcludeScopes: tt.exclude})
+			require.NoError(t, err)
+			for _, title := range []string{
+				"feat(cli): foo",
+				"feat(web): foo",
+				"feat(cli, web): foo",
+				"fix(api)!: foo",
+				"fix: foo",
+				"update readme",
+			} {
+				excluded, reason := filter.excludes(git.Commit{Title: title})
+				require.Equal(t, tt.excluded[title] != "", excluded, title)
+				require.Equal(t, tt.excluded[title], reason, title)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := newScopeFilter(Options{IncludeScopes: []string{"[cli"}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,406 @@
+// Package semtag computes semantic versions from the tags and Conventional
+// Commits of the git repository in the current directory.
+//
+// It is the library behind the semtag command, for tools that need the
+// version computation without shelling out to it.
+package semtag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// Commit is a commit with a hash, title (first line of the message), and body
+// (rest of the message, not including the title).
+type Commit = git.Commit
+
+var (
+	// ErrNotRepository is returned when the current directory is not inside a
+	// git repository.
+	ErrNotRepository = git.ErrNotRepository
+
+	// ErrShallowRepository is returned when the repository is a shallow clone
+	// and Options.Unshallow is not set.
+	ErrShallowRepository = git.ErrShallowRepository
+
+	// ErrGitMissing is returned when the git executable can't be found.
+	ErrGitMissing = git.ErrGitMissing
+
+	// ErrNoTagsFound is returned when Options.RequireTag is set and there are
+	// no version tags to start from.
+	ErrNoTagsFound = errors.New("no version tags found")
+
+	// ErrNoReleaseNeeded is returned when no commit since the latest release
+	// warrants a new version.
+	ErrNoReleaseNeeded = errors.New("no release needed")
+)
+
+// GitCommandError is returned when a git command fails, with its arguments,
+// standard error and exit code.
+type GitCommandError = git.CommandError
+
+// TagPatternError is returned when there are tags, but none of them match
+// Options.Pattern.
+type TagPatternError = git.NoMatchError
+
+// TagParseError is returned when the tag a version is based on is not a valid
+// semantic version once the prefix is removed.
+type TagParseError struct {
+	Tag string
+	Err error
+}
+
+func (e *TagParseError) Error() string {
+	return fmt.Sprintf("could not parse tag '%s': %v", e.Tag, e.Err)
+}
+
+func (e *TagParseError) Unwrap() error {
+	return e.Err
+}
+
+// OptionError is returned when an option has to be set for the operation to
+// succeed, e.g. Options.Unshallow in a shallow clone.
+type OptionError struct {
+	// Field is the path of the option in Options, e.g. "PullRequest.Number".
+	Field string
+	// Alternative is another way to fix the error, if any.
+	Alternative string
+	Err         error
+}
+
+func (e *OptionError) Error() string {
+	return e.Suggest("set Options." + e.Field)
+}
+
+// Suggest formats the error with fix as the way to set the option, so that
+// callers can name it as their users know it, e.g. as a command line flag.
+func (e *OptionError) Suggest(fix string) string {
+	if e.Alternative != "" {
+		fix = e.Alternative + " or " + fix
+	}
+	return fmt.Sprintf("%v: %s", e.Err, fix)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// Options configures how versions are computed. The zero value is valid and
+// matches the defaults of the semtag command.
+type Options struct {
+	// Prefix is prepended to versions to form tag names, e.g. "v".
+	Prefix string
+	// Pattern is a glob tags must match to be considered, e.g. "v*".
+	Pattern string
+	// Ref is the revision to compute the version of, HEAD when empty. Tags
+	// and commits are the ones reachable from it.
+	Ref string
+	// Paths restricts the commits considered to the ones touching them.
+	Paths []string
+	// IncludeScopes restricts the commits considered to the ones with a
+	// Conventional Commits scope matching one of these globs, e.g. "cli".
+	// ExcludeScopes leaves out the ones whose scopes all match one of them.
+	// Both combine with Paths.
+	IncludeScopes []string
+	ExcludeScopes []string
+	// MergeStrategy decides how merge commits and the commits of merged
+	// branches are considered. The zero value means MergeStrategyAll.
+	MergeStrategy MergeStrategy
+	// Branch overrides the detection of the current branch, which decides
+	// the pre-release suffix and the maintenance line.
+	Branch string
+	// BranchSuffixes maps branches (or "prefix/*" patterns) to pre-release
+	// suffixes, on top of the defaults and the SVU_BRANCH_* variables.
+	BranchSuffixes map[string]string
+	// MaintenanceBranches are branches maintaining older lines of versions.
+	// Their versions are stable, and Next fails with a MaintenanceError
+	// rather than leave the line or reuse a version tagged elsewhere.
+	MaintenanceBranches []MaintenanceBranch
+	// PullRequest enables pull request preview versions.
+	PullRequest PullRequestOptions
+	// Unshallow fetches more history when the repository is a shallow clone,
+	// instead of failing with ErrShallowRepository.
+	Unshallow bool
+	// Rules decides which commits bump which part of the version. The zero
+	// value means DefaultRules.
+	Rules Rules
+	// ReleaseAs forces the next version, which must be greater than the
+	// current one. It takes precedence over Bump.
+	ReleaseAs string
+	// Bump forces the part of the version to increment, regardless of the
+	// commits. It takes precedence over Release-As commit footers, which
+	// themselves take precedence over Rules.
+	Bump Bump
+	// SkipValidation makes Next return versions failing Validate, instead of
+	// a ValidationError.
+	SkipValidation bool
+	// VerifyAPI compares the exported Go API of the previous stable tag and
+	// Ref with DiffAPI, to catch commits understating their changes.
+	VerifyAPI APIVerification
+	// Policy restricts the versions Next returns, failing with a PolicyError
+	// otherwise.
+	Policy Policy
+	// RequireTag fails with ErrNoTagsFound when there are no tags, instead of
+	// starting from 0.0.0.
+	RequireTag bool
+	// Links configures how issues and pull requests referenced by commits are
+	// linked in release notes and changelogs.
+	Links LinkOptions
+	// ExcludeBots leaves bots, such as dependabot[bot], out of the
+	// contributors of release notes.
+	ExcludeBots bool
+}
+
+// ref returns the revision versions are computed for.
+func (o Options) ref() string {
+	if o.Ref == "" {
+		return "HEAD"
+	}
+	return o.Ref
+}
+
+// branch returns the branch of the revision versions are computed for.
+func (o Options) branch(ctx context.Context) string {
+	if o.Branch != "" {
+		return o.Branch
+	}
+	return git.BranchAt(ctx, o.Ref)
+}
+
+// Result is a computed version.
+type Result struct {
+	// Version is the version, including its pre-release suffix if any.
+	Version semver.Version
+	// Tag is Version with the prefix, as it should be tagged.
+	Tag string
+	// Previous is the stable version Version was computed from, and
+	// PreviousTag its tag, which is empty when there are no tags yet.
+	Previous    semver.Version
+	PreviousTag string
+	// Bump is the increment applied to Previous.
+	Bump Bump
+	// Commit is the commit which decided Bump, nil for BumpNone or when
+	// forced through Options.
+	Commit *Commit
+	// Override tells what forced the version, if anything.
+	Override Override
+	// API is the difference between the exported Go API of PreviousTag and
+	// the revision, set with Options.VerifyAPI.
+	API *APIDiff
+	// Validation is the outcome of validating Version, whose problems are
+	// only warnings, as the other ones fail Next. It is nil when no release
+	// is needed or with Options.SkipValidation.
+	Validation *Validation
+}
+
+// APIBumpTooLow reports whether the changes of API require a higher bump than
+// Bump.
+func (r *Result) APIBumpTooLow() bool {
+	return r.API != nil && r.Bump < r.API.Bump
+}
+
+// ReleaseNeeded reports whether any commit since the previous release
+// warrants a new version. When it doesn't, Version is the previous version
+// (with a pre-release suffix on pre-release branches), which is already
+// tagged.
+func (r *Result) ReleaseNeeded() bool {
+	return r.Bump != BumpNone || r.Override != OverrideNone
+}
+
+// Reason explains in a sentence why a release is or isn't needed.
+func (r *Result) Reason() string {
+	since := "since the first commit"
+	if r.PreviousTag != "" {
+		since = "since " + r.PreviousTag
+	}
+	switch {
+	case !r.ReleaseNeeded():
+		return "no commit " + since + " warrants a release"
+	case r.Override == OverrideTrailer:
+		return fmt.Sprintf("%s release forced %s by %s: %s %s", r.Bump, since, r.Override, shortSHA(r.Commit.SHA), r.Commit.Title)
+	case r.Override != OverrideNone:
+		return fmt.Sprintf("%s release forced %s by %s", r.Bump, since, r.Override)
+	}
+	return fmt.Sprintf("%s release needed %s: %s %s", r.Bump, since, shortSHA(r.Commit.SHA), r.Commit.Title)
+}
+
+// Next computes the next version based on the commits since the latest stable
+// tag reachable from HEAD (or opts.Ref) and on the current branch.
+func Next(ctx context.Context, opts Options) (*Result, error) {
+	if err := prepare(ctx, opts, func() (bool, error) {
+		tag, err := git.DescribeStableTag(ctx, git.TagModeCurrent, opts.Ref, opts.Prefix, opts.Pattern)
+		return tag != "", err
+	}); err != nil {
+		return nil, err
+	}
+
+	stableTag, err := git.DescribeStableTag(ctx, git.TagModeCurrent, opts.Ref, opts.Prefix, opts.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stable tag: %w", err)
+	}
+
+	if stableTag == "" && opts.RequireTag {
+		return nil, ErrNoTagsFound
+	}
+
+	current, err := versionFromTag(stableTag, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := newHistorySource(ctx, stableTag, opts.ref(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decided, err := decide(current, commits, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var api *APIDiff
+	if opts.VerifyAPI != APIVerificationOff && stableTag != "" {
+		api, err = verifyAPI(ctx, opts, stableTag, decided.bump)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := opts.Policy.enforce(decided); err != nil {
+		return nil, err
+	}
+
+	line, branch, err := maintenanceLineFor(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	next := decided.version
+	if line != nil {
+		err = checkMaintenance(ctx, *line, branch, decided, opts)
+	} else {
+		next, err = applyBranchSuffix(ctx, decided.version, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var validation *Validation
+	releaseNeeded := decided.bump != BumpNone || decided.override != OverrideNone
+	if releaseNeeded && !opts.SkipValidation {
+		validation, err = validate(ctx, opts, &next, line)
+		if err != nil {
+			return nil, err
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Result{
+		Version:     next,
+		Tag:         formatTag(opts.Prefix, next),
+		Previous:    *current,
+		PreviousTag: stableTag,
+		Bump:        decided.bump,
+		Commit:      decided.commit,
+		Override:    decided.override,
+		API:         api,
+		Validation:  validation,
+	}, nil
+}
+
+// Current returns the highest version tag reachable from HEAD (or opts.Ref),
+// including pre-releases, or 0.0.0 if there is none.
+func Current(ctx context.Context, opts Options) (*Result, error) {
+	if err := prepare(ctx, opts, func() (bool, error) {
+		tag, err := git.DescribeTag(ctx, git.TagModeCurrent, opts.Ref, opts.Prefix, opts.Pattern)
+		return tag != "", err
+	}); err != nil {
+		return nil, err
+	}
+
+	currentTag, err := git.DescribeTag(ctx, git.TagModeCurrent, opts.Ref, opts.Prefix, opts.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current tag: %w", err)
+	}
+
+	if currentTag == "" && opts.RequireTag {
+		return nil, ErrNoTagsFound
+	}
+
+	current, err := versionFromTag(currentTag, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := currentTag
+	if tag == "" {
+		tag = formatTag(opts.Prefix, *current)
+	}
+
+	return &Result{
+		Version:     *current,
+		Tag:         tag,
+		Previous:    *current,
+		PreviousTag: currentTag,
+	}, nil
+}
+
+// prepare checks the repository can be used to compute versions. Shallow
+// clones would silently give wrong versions, so they are refused unless
+// opts.Unshallow is set, in which case the history is deepened until
+// baseTagReachable reports true.
+func prepare(ctx context.Context, opts Options, baseTagReachable func() (bool, error)) error {
+	if err := git.EnsureRepo(ctx); err != nil {
+		return err
+	}
+
+	shallow, err := git.IsShallow(ctx)
+	if err != nil {
+		return err
+	}
+	if !shallow {
+		return nil
+	}
+	if !opts.Unshallow {
+		return &OptionError{
+			Field:       "Unshallow",
+			Alternative: "fetch the full history (e.g. fetch-depth: 0)",
+			Err:         ErrShallowRepository,
+		}
+	}
+	return git.Deepen(ctx, baseTagReachable)
+}
+
+// IsStableTag reports whether a tag is a stable release rather than a
+// pre-release.
+func IsStableTag(tag string) bool {
+	return git.IsStableTag(tag)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func formatTag(prefix string, version semver.Version) string {
+	return prefix + version.String()
+}
+
+func versionFromTag(tag, prefix string) (*semver.Version, error) {
+	if tag == "" {
+		return semver.MustParse("0.0.0"), nil
+	}
+	version, err := semver.NewVersion(strings.TrimPrefix(tag, prefix))
+	if err != nil {
+		return nil, &TagParseError{Tag: tag, Err: err}
+	}
+	return version, nil
+}
@@ -0,0 +1,245 @@
+package semtag
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "chore: foobar")
+		gitTag(tb, "v1.2.3")
+		gitCommit(tb, "fix: foo")
+		gitCommit(tb, "feat: bar")
+		gitCommit(tb, "chore: baz")
+	}
+
+	t.Run("feature", func(t *testing.T) {
+		setup(t)
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0", result.Tag)
+		require.Equal(t, "1.2.3", result.Previous.String())
+		require.Equal(t, "v1.2.3", result.PreviousTag)
+		require.Equal(t, BumpMinor, result.Bump)
+		require.Equal(t, "feat: bar", result.Commit.Title)
+	})
+
+	t.Run("custom rules", func(t *testing.T) {
+		setup(t)
+		result, err := Next(context.Background(), Options{
+			Prefix: "v",
+			Branch: "main",
+			Rules:  Rules{MinorTypes: []string{"chore"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0", result.Tag)
+		require.Equal(t, "chore: baz", result.Commit.Title)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat(web)!: redesign")
+		gitCommit(t, "fix(cli): flag")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main", IncludeScopes: []string{"cli"}})
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.4", result.Tag)
+		require.Equal(t, "fix(cli): flag", result.Commit.Title)
+
+		result, err = Next(context.Background(), Options{Prefix: "v", Branch: "main", ExcludeScopes: []string{"web"}})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0", result.Tag)
+	})
+
+	t.Run("release-as trailer after a breaking change", func(t *testing.T) {
+		setup(t)
+		gitCommit(t, "feat!: drop it")
+		gitCommit(t, "chore(web): release\n\nRelease-As: 5.0.0")
+		gitCommit(t, "chore(cli): release\n\nrelease-as: 3.0.0")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v3.0.0", result.Tag)
+		require.Equal(t, OverrideTrailer, result.Override)
+		require.Equal(t, "chore(cli): release", result.Commit.Title)
+
+		result, err = Next(context.Background(), Options{Prefix: "v", Branch: "main", ExcludeScopes: []string{"cli"}})
+		require.NoError(t, err)
+		require.Equal(t, "v5.0.0", result.Tag)
+
+		// A breaking change supersedes the footers committed before it.
+		gitCommit(t, "feat(api)!: drop it again")
+		result, err = Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+		require.Equal(t, OverrideNone, result.Override)
+	})
+
+	t.Run("ref", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.3.0")
+		gitRun(t, "switch", "-c", "release/1.x", "HEAD~2")
+		gitCommit(t, "fix: backport")
+		gitRun(t, "switch", "main")
+		gitCommit(t, "feat!: breaking")
+
+		// The branch, and so the suffix, is the one of the ref.
+		result, err := Next(context.Background(), Options{Prefix: "v", Ref: "release/1.x"})
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.4-rc.1", result.Tag)
+		require.Equal(t, "fix: backport", result.Commit.Title)
+
+		result, err = Next(context.Background(), Options{Prefix: "v", Ref: "HEAD~1", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0", result.Tag)
+		require.False(t, result.ReleaseNeeded())
+
+		result, err = Current(context.Background(), Options{Prefix: "v", Ref: "release/1.x"})
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.3", result.Tag)
+	})
+
+	t.Run("no release needed", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.3.0")
+		gitCommit(t, "docs: foo")
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.False(t, result.ReleaseNeeded())
+		require.Equal(t, "v1.3.0", result.Tag)
+		require.Equal(t, "no commit since v1.3.0 warrants a release", result.Reason())
+	})
+
+	t.Run("release reason", func(t *testing.T) {
+		setup(t)
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.True(t, result.ReleaseNeeded())
+		require.Regexp(t, `^minor release needed since v1\.2\.3: [0-9a-f]{7} feat: bar$`, result.Reason())
+	})
+
+	t.Run("branch suffix", func(t *testing.T) {
+		setup(t)
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "develop"})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0-beta.1", result.Tag)
+	})
+
+	t.Run("pull request", func(t *testing.T) {
+		setup(t)
+		gitRun(t, "switch", "-c", "feature")
+		gitCommit(t, "fix: a")
+		gitCommit(t, "fix: b")
+		gitRun(t, "switch", "main")
+		gitCommit(t, "chore: main moves on")
+		gitRun(t, "switch", "feature")
+
+		// The count is the number of commits since the merge base.
+		result, err := Next(context.Background(), Options{Prefix: "v", PullRequest: PullRequestOptions{Enabled: true, Number: 7, Base: "main"}})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0-pr.7.2", result.Tag)
+
+		// The CI may tell it built the merge ref while the head was checked
+		// out.
+		t.Setenv("GITHUB_REF", "refs/pull/7/merge")
+		t.Setenv("GITHUB_BASE_REF", "main")
+		result, err = Next(context.Background(), Options{Prefix: "v", PullRequest: PullRequestOptions{Enabled: true}})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0-pr.7.2", result.Tag)
+
+		// On a merge ref, the head of the pull request is its second parent.
+		gitRun(t, "switch", "--detach", "main")
+		gitRun(t, "merge", "--no-ff", "-m", "Merge feature", "feature")
+		gitRun(t, "update-ref", "refs/pull/7/merge", "HEAD")
+		result, err = Next(context.Background(), Options{Prefix: "v", PullRequest: PullRequestOptions{Enabled: true, Base: "main"}})
+		require.NoError(t, err)
+		require.Equal(t, "v1.3.0-pr.7.2", result.Tag)
+	})
+
+	t.Run("not a repository", func(t *testing.T) {
+		tempDir(t)
+		_, err := Next(context.Background(), Options{})
+		require.ErrorIs(t, err, ErrNotRepository)
+	})
+
+	t.Run("unparseable tag", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.2.3.4")
+		_, err := Current(context.Background(), Options{Prefix: "v", Pattern: "v1.2.3.*"})
+		var parseErr *TagParseError
+		require.ErrorAs(t, err, &parseErr)
+		require.Equal(t, "v1.2.3.4", parseErr.Tag)
+	})
+}
+
+func TestCurrent(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "chore: foobar")
+	result, err := Current(context.Background(), Options{Prefix: "v"})
+	require.NoError(t, err)
+	require.Equal(t, "v0.0.0", result.Tag)
+
+	gitTag(t, "v1.2.3")
+	gitCommit(t, "fix: foo")
+	gitTag(t, "v1.2.4-beta.1")
+
+	result, err = Current(context.Background(), Options{Prefix: "v"})
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.4-beta.1", result.Tag)
+	require.Equal(t, "1.2.4-beta.1", result.Version.String())
+}
+
+func tempDir(tb testing.TB) string {
+	tb.Helper()
+	previous, err := os.Getwd()
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		require.NoError(tb, os.Chdir(previous))
+	})
+	dir := tb.TempDir()
+	require.NoError(tb, os.Chdir(dir))
+	return dir
+}
+
+func tempRepo(tb testing.TB) string {
+	tb.Helper()
+	// The branch and pull request must not be detected from the CI running
+	// the tests.
+	for _, name := range git.CIVariables() {
+		tb.Setenv(name, "")
+	}
+	dir := tempDir(tb)
+	gitRun(tb, "init", "--initial-branch", "main")
+	return dir
+}
+
+func gitCommit(tb testing.TB, msg string) {
+	tb.Helper()
+	gitRun(tb, "commit", "--allow-empty", "-m", msg)
+}
+
+func gitTag(tb testing.TB, tag string) {
+	tb.Helper()
+	gitRun(tb, "tag", tag)
+}
+
+func gitRun(tb testing.TB, args ...string) string {
+	tb.Helper()
+	allArgs := []string{
+		"-c", "user.name=svu",
+		"-c", "user.email=svu@example.com",
+		"-c", "commit.gpgSign=false",
+		"-c", "tag.gpgSign=false",
+	}
+	out, err := exec.Command("git", append(allArgs, args...)...).CombinedOutput()
+	require.NoError(tb, err, string(out))
+	return string(out)
+}
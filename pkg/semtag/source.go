@@ -0,0 +1,81 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// commitSource provides the commits the next version is decided from, newest
+// first. Iterating over them may read them as it goes, so that deciding can
+// stop as soon as it is done.
+type commitSource interface {
+	// commits iterates over all the commits. An error ends the iteration.
+	commits() iter.Seq2[git.Commit, error]
+}
+
+// commitList is a commitSource over commits already read.
+type commitList []git.Commit
+
+func (l commitList) commits() iter.Seq2[git.Commit, error] {
+	return func(yield func(git.Commit, error) bool) {
+		for _, commit := range l {
+			if !yield(commit, nil) {
+				return
+			}
+		}
+	}
+}
+
+// historySource is a commitSource reading the commits of commitsBetween from
+// git as they are needed.
+type historySource struct {
+	ctx       context.Context
+	tag, rev  string
+	log       git.LogOptions
+	mergeBody bool
+	filter    scopeFilter
+}
+
+func newHistorySource(ctx context.Context, tag, rev string, opts Options) (historySource, error) {
+	strategy, err := ParseMergeStrategy(string(opts.MergeStrategy))
+	if err != nil {
+		return historySource{}, err
+	}
+	filter, err := newScopeFilter(opts)
+	if err != nil {
+		return historySource{}, err
+	}
+	return historySource{
+		ctx:       ctx,
+		tag:       tag,
+		rev:       rev,
+		log:       git.LogOptions{Paths: opts.Paths, FirstParent: strategy != MergeStrategyAll},
+		mergeBody: strategy == MergeStrategyMergeBody,
+		filter:    filter,
+	}, nil
+}
+
+// commits iterates over the commits git logs, following the merge strategy
+// and leaving out the ones excluded by their scope.
+func (s historySource) commits() iter.Seq2[git.Commit, error] {
+	return func(yield func(git.Commit, error) bool) {
+		for commit, err := range git.Log(s.ctx, s.tag, s.rev, s.log) {
+			if err != nil {
+				yield(git.Commit{}, fmt.Errorf("failed to get changelog: %w", err))
+				return
+			}
+			if s.mergeBody {
+				commit = titleFromMergeBody(commit)
+			}
+			if excluded, _ := s.filter.excludes(commit); excluded {
+				continue
+			}
+			if !yield(commit, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package semtag
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistorySource(t *testing.T) {
+	tempRepo(t)
+	gitCommit(t, "fix(web): a")
+	gitCommit(t, "feat(cli): b")
+	gitCommit(t, "chore(cli): c")
+
+	source, err := newHistorySource(context.Background(), "", "HEAD", Options{IncludeScopes: []string{"cli"}})
+	require.NoError(t, err)
+
+	var titles []string
+	for commit, err := range source.commits() {
+		require.NoError(t, err)
+		titles = append(titles, commit.Title)
+	}
+	require.Equal(t, []string{"chore(cli): c", "feat(cli): b"}, titles)
+
+	for commit, err := range source.commits() {
+		require.NoError(t, err)
+		require.Equal(t, "chore(cli): c", commit.Title)
+		break
+	}
+}
+
+// benchmarkCommits is the size of the history generated for benchmarks.
+const benchmarkCommits = 200_000
+
+// BenchmarkNextFirstRelease computes the first release of a repository with a
+// long history and a breaking change near HEAD, which only needs the latest
+// commits, against reading the whole history as Notes does.
+func BenchmarkNextFirstRelease(b *testing.B) {
+	tempRepo(b)
+	fastImport(b, benchmarkCommits, func(i int) string {
+		switch i {
+		case benchmarkCommits - 10:
+			return "feat!: drop the old API"
+		case benchmarkCommits - 1:
+			return "fix: latest"
+		}
+		if i%3 == 0 {
+			return fmt.Sprintf("feat: feature %d", i)
+		}
+		return fmt.Sprintf("fix: fix %d", i)
+	})
+	gitRun(b, "reset", "--hard", "main")
+	opts := Options{Branch: "main"}
+
+	b.Run("short-circuit", func(b *testing.B) {
+		for range b.N {
+			result, err := Next(context.Background(), opts)
+			require.NoError(b, err)
+			require.Equal(b, "1.0.0", result.Version.String())
+		}
+	})
+
+	b.Run("whole history", func(b *testing.B) {
+		for range b.N {
+			commits, err := commitsBetween(context.Background(), "", "HEAD", opts)
+			require.NoError(b, err)
+			require.Len(b, commits, benchmarkCommits)
+			classified, err := opts.Rules.classify(commitList(commits), false)
+			require.NoError(b, err)
+			require.Equal(b, BumpMajor, classified.bump)
+		}
+	})
+}
+
+// fastImport adds n commits to main with git fast-import, whose messages are
+// given by message, the oldest first.
+func fastImport(tb testing.TB, n int, message func(i int) string) {
+	tb.Helper()
+	cmd := exec.Command("git", "fast-import", "--quiet")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(tb, err)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	require.NoError(tb, cmd.Start())
+
+	w := bufio.NewWriter(stdin)
+	for i := range n {
+		msg := message(i)
+		fmt.Fprintf(w, "commit refs/heads/main\ncommitter svu <svu@example.com> %d +0000\ndata %d\n%s\n\n", 1700000000+i, len(msg), msg)
+	}
+	require.NoError(tb, w.Flush())
+	require.NoError(tb, stdin.Close())
+	require.NoError(tb, cmd.Wait(), stderr.String())
+}
@@ -0,0 +1,217 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/google-internal/semtag/internal/git"
+)
+
+// ProblemKind is the kind of a Problem found by Validate.
+type ProblemKind string
+
+const (
+	// ProblemTagExists is a version whose tag already exists.
+	ProblemTagExists ProblemKind = "tag-exists"
+	// ProblemNotGreater is a version lower than, or equal to, an existing
+	// tag of its stream, e.g. tagged on another branch.
+	ProblemNotGreater ProblemKind = "not-greater"
+	// ProblemGap is a stable version skipping versions, e.g. 1.5.0 after
+	// 1.3.0. It is a warning.
+	ProblemGap ProblemKind = "gap"
+	// ProblemDuplicate is several tags of the same version, e.g. v1.2.3 and
+	// v1.2.3+build.1. It is a warning.
+	ProblemDuplicate ProblemKind = "duplicate"
+)
+
+// Warning reports whether problems of this kind are only warnings, which
+// don't prevent tagging a version.
+func (k ProblemKind) Warning() bool {
+	return k == ProblemGap || k == ProblemDuplicate
+}
+
+// Problem is an issue found by Validate.
+type Problem struct {
+	Kind ProblemKind
+	// Tags are the existing tags involved.
+	Tags    []string
+	Message string
+}
+
+// Validation is the outcome of Validate.
+type Validation struct {
+	// Tag is the tag of the validated version, empty when only the existing
+	// tags were checked.
+	Tag string
+	// Stream is the pre-release identifier shared by the tags the version is
+	// compared to, e.g. "beta" for 1.3.0-beta.2, and empty for stable
+	// versions.
+	Stream string
+	// Line is the version line of the maintenance branch the tags are
+	// restricted to, if any.
+	Line string
+	// Latest is the highest existing tag of the stream, empty when there is
+	// none.
+	Latest   string
+	Problems []Problem
+}
+
+// Err returns a ValidationError with the problems which are not warnings, or
+// nil when there are none.
+func (v *Validation) Err() error {
+	var problems []Problem
+	for _, problem := range v.Problems {
+		if !problem.Kind.Warning() {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Tag: v.Tag, Problems: problems}
+}
+
+// ValidationError is returned by Next when the next version can't be tagged,
+// unless Options.SkipValidation is set.
+type ValidationError struct {
+	Tag      string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.Message)
+	}
+	if e.Tag == "" {
+		return "invalid version tags: " + strings.Join(messages, "; ")
+	}
+	return fmt.Sprintf("version tag %s is invalid: %s", e.Tag, strings.Join(messages, "; "))
+}
+
+// Validate checks version can be tagged: its tag must not exist, and it must
+// be greater than every tag of its stream across all branches, i.e. the
+// stable tags for stable versions and the tags with the same pre-release
+// identifier for pre-releases. On maintenance branches, only the tags of
+// their line are compared. The tags of the stream are also checked for gaps
+// and duplicates, and so is version. When version is nil, only the existing
+// stable tags are checked.
+func Validate(ctx context.Context, opts Options, version *semver.Version) (*Validation, error) {
+	line, _, err := maintenanceLineFor(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return validate(ctx, opts, version, line)
+}
+
+func validate(ctx context.Context, opts Options, version *semver.Version, line *maintenanceLine) (*Validation, error) {
+	releases, err := History(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &Validation{}
+	if version != nil {
+		validation.Tag = formatTag(opts.Prefix, *version)
+		validation.Stream = streamOf(version)
+	}
+	if line != nil {
+		validation.Line = line.name
+	}
+
+	// The tags of the stream, latest first as History returns them.
+	var tags []string
+	var versions []*semver.Version
+	for _, release := range releases {
+		v, err := semver.NewVersion(release.Version)
+		if err != nil {
+			return nil, err
+		}
+		if streamOf(v) != validation.Stream || (line != nil && !line.contains(*v)) {
+			continue
+		}
+		tags = append(tags, release.Tag)
+		versions = append(versions, v)
+	}
+	if len(tags) > 0 {
+		validation.Latest = tags[0]
+	}
+
+	if version != nil {
+		exists, err := git.HasTag(ctx, validation.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			validation.Problems = append(validation.Problems, Problem{
+				Kind:    ProblemTagExists,
+				Tags:    []string{validation.Tag},
+				Message: fmt.Sprintf("tag %s already exists", validation.Tag),
+			})
+		}
+		if len(versions) > 0 && !version.GreaterThan(versions[0]) {
+			if !exists || tags[0] != validation.Tag {
+				validation.Problems = append(validation.Problems, Problem{
+					Kind:    ProblemNotGreater,
+					Tags:    []string{tags[0]},
+					Message: fmt.Sprintf("version %s is not greater than the existing tag %s", version, tags[0]),
+				})
+			}
+		} else if len(versions) > 0 && validation.Stream == "" && !isIncrement(versions[0], version) {
+			validation.Problems = append(validation.Problems, Problem{
+				Kind:    ProblemGap,
+				Tags:    []string{tags[0]},
+				Message: fmt.Sprintf("version %s skips versions after %s", version, tags[0]),
+			})
+		}
+	}
+
+	// Older tags last, so problems are reported oldest first.
+	for i := len(versions) - 1; i > 0; i-- {
+		older, newer := versions[i], versions[i-1]
+		switch {
+		case older.Equal(newer):
+			validation.Problems = append(validation.Problems, Problem{
+				Kind:    ProblemDuplicate,
+				Tags:    []string{tags[i], tags[i-1]},
+				Message: fmt.Sprintf("tags %s and %s are the same version", tags[i], tags[i-1]),
+			})
+		case validation.Stream == "" && !isIncrement(older, newer):
+			validation.Problems = append(validation.Problems, Problem{
+				Kind:    ProblemGap,
+				Tags:    []string{tags[i], tags[i-1]},
+				Message: fmt.Sprintf("tag %s skips versions after %s", tags[i-1], tags[i]),
+			})
+		}
+	}
+	return validation, nil
+}
+
+// streamOf returns the pre-release identifier of a version without its
+// trailing number, e.g. "beta" for 1.3.0-beta.2 and "pr.482" for
+// 1.5.0-pr.482.3.
+func streamOf(version *semver.Version) string {
+	prerelease := version.Prerelease()
+	if i := strings.LastIndex(prerelease, "."); i >= 0 {
+		if _, err := strconv.ParseUint(prerelease[i+1:], 10, 64); err == nil {
+			return prerelease[:i]
+		}
+	}
+	return prerelease
+}
+
+// isIncrement reports whether next is a major, minor or patch increment of
+// previous, ignoring pre-releases and build metadata.
+func isIncrement(previous, next *semver.Version) bool {
+	core := func(v semver.Version) string {
+		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+	}
+	target := core(*next)
+	return target == core(previous.IncPatch()) ||
+		target == core(previous.IncMinor()) ||
+		target == core(previous.IncMajor())
+}
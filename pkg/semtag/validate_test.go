@@ -0,0 +1,127 @@
+package semtag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	setup := func(tb testing.TB) {
+		tb.Helper()
+		tempRepo(tb)
+		gitCommit(tb, "feat: first")
+		gitTag(tb, "v1.0.0")
+		gitCommit(tb, "feat: second")
+		gitTag(tb, "v1.1.0")
+		gitTag(tb, "v1.2.0-beta.1")
+	}
+	kinds := func(validation *Validation) []ProblemKind {
+		var kinds []ProblemKind
+		for _, problem := range validation.Problems {
+			kinds = append(kinds, problem.Kind)
+		}
+		return kinds
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		setup(t)
+		validation, err := Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("1.2.0"))
+		require.NoError(t, err)
+		require.Empty(t, validation.Problems)
+		require.Equal(t, "v1.2.0", validation.Tag)
+		require.Equal(t, "v1.1.0", validation.Latest)
+		require.NoError(t, validation.Err())
+	})
+
+	t.Run("tag exists", func(t *testing.T) {
+		setup(t)
+		validation, err := Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("1.1.0"))
+		require.NoError(t, err)
+		require.Equal(t, []ProblemKind{ProblemTagExists}, kinds(validation))
+		require.EqualError(t, validation.Err(), "version tag v1.1.0 is invalid: tag v1.1.0 already exists")
+	})
+
+	t.Run("not greater", func(t *testing.T) {
+		setup(t)
+		gitRun(t, "switch", "-c", "other", "HEAD~1")
+		gitCommit(t, "feat: elsewhere")
+		gitTag(t, "v1.2.0")
+		gitRun(t, "switch", "main")
+
+		validation, err := Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("1.1.1"))
+		require.NoError(t, err)
+		require.Equal(t, []ProblemKind{ProblemNotGreater}, kinds(validation))
+		require.Equal(t, []string{"v1.2.0"}, validation.Problems[0].Tags)
+		require.Error(t, validation.Err())
+	})
+
+	t.Run("pre-release stream", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.3.0-rc.1")
+
+		// Beta versions are only compared to beta tags.
+		validation, err := Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("1.2.0-beta.2"))
+		require.NoError(t, err)
+		require.Empty(t, validation.Problems)
+		require.Equal(t, "beta", validation.Stream)
+		require.Equal(t, "v1.2.0-beta.1", validation.Latest)
+
+		validation, err = Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("1.1.0-beta.2"))
+		require.NoError(t, err)
+		require.Equal(t, []ProblemKind{ProblemNotGreater}, kinds(validation))
+	})
+
+	t.Run("gaps and duplicates", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.1.0+build.1")
+		gitTag(t, "v1.4.0")
+
+		validation, err := Validate(context.Background(), Options{Prefix: "v"}, semver.MustParse("3.0.0"))
+		require.NoError(t, err)
+		require.Equal(t, []ProblemKind{ProblemGap, ProblemDuplicate, ProblemGap}, kinds(validation))
+		require.Equal(t, "version 3.0.0 skips versions after v1.4.0", validation.Problems[0].Message)
+		require.Equal(t, []string{"v1.1.0+build.1", "v1.4.0"}, validation.Problems[2].Tags)
+		// Warnings don't prevent tagging.
+		require.NoError(t, validation.Err())
+
+		validation, err = Validate(context.Background(), Options{Prefix: "v"}, nil)
+		require.NoError(t, err)
+		require.Empty(t, validation.Tag)
+		require.Equal(t, []ProblemKind{ProblemDuplicate, ProblemGap}, kinds(validation))
+	})
+
+	t.Run("next", func(t *testing.T) {
+		setup(t)
+		gitRun(t, "switch", "-c", "other", "HEAD~1")
+		gitCommit(t, "feat: elsewhere")
+		gitTag(t, "v1.2.0")
+		gitRun(t, "switch", "main")
+		gitCommit(t, "feat: third")
+
+		_, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Equal(t, "v1.2.0", validationErr.Tag)
+		require.EqualError(t, err, "version tag v1.2.0 is invalid: tag v1.2.0 already exists")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main", SkipValidation: true})
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.0", result.Tag)
+		require.Nil(t, result.Validation)
+	})
+
+	t.Run("next warnings", func(t *testing.T) {
+		setup(t)
+		gitTag(t, "v1.1.0+build.1")
+		gitCommit(t, "feat!: third")
+
+		result, err := Next(context.Background(), Options{Prefix: "v", Branch: "main"})
+		require.NoError(t, err)
+		require.Equal(t, "v2.0.0", result.Tag)
+		require.Equal(t, []ProblemKind{ProblemDuplicate}, kinds(result.Validation))
+		require.ElementsMatch(t, []string{"v1.1.0", "v1.1.0+build.1"}, result.Validation.Problems[0].Tags)
+	})
+}
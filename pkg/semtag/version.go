@@ -0,0 +1,363 @@
+package semtag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google-internal/semtag/internal/git"
+)
+
+var (
+	breakingBody = regexp.MustCompile("(?m).*BREAKING[ -]CHANGE:.*")
+	breaking     = regexp.MustCompile(`(?im).*(\w+)(\(.*\))?!:.*`)
+	feature      = regexp.MustCompile(`(?im).*feat(\(.*\))?:.*`)
+	patch        = regexp.MustCompile(`(?im).*fix(\(.*\))?:.*`)
+)
+
+func applyBranchSuffix(ctx context.Context, version semver.Version, opts Options) (semver.Version, error) {
+	if opts.PullRequest.Enabled {
+		return applyPullRequestSuffix(ctx, version, opts.ref(), opts.PullRequest)
+	}
+
+	branch := opts.branch(ctx)
+	resolver := newBranchSuffixResolver(opts.BranchSuffixes)
+	branchSuffix := resolver.suffixForBranch(branch)
+	if branchSuffix == "" {
+		return version, nil
+	}
+
+	existingTag, err := git.GetLatestTagWithSuffix(ctx, branchSuffix, git.TagModeCurrent, opts.Ref, opts.Prefix, opts.Pattern)
+	if err != nil {
+		return version, fmt.Errorf("failed to get latest tag with suffix '%s': %w", branchSuffix, err)
+	}
+
+	var nextSuffix string
+	if existingTag == "" {
+		nextSuffix = branchSuffix + ".1"
+	} else {
+		existingVersion, err := versionFromTag(existingTag, opts.Prefix)
+		if err != nil {
+			return version, err
+		}
+
+		if existingVersion.Major() == version.Major() &&
+			existingVersion.Minor() == version.Minor() &&
+			existingVersion.Patch() == version.Patch() {
+			prerelease := existingVersion.Prerelease()
+			if prerelease == "" {
+				nextSuffix = branchSuffix + ".1"
+			} else {
+				nextNumber := getNextPrereleaseNumber(prerelease, branchSuffix)
+				nextSuffix = branchSuffix + "." + strconv.Itoa(nextNumber)
+			}
+		} else {
+			nextSuffix = branchSuffix + ".1"
+		}
+	}
+
+	withSuffix, err := version.SetPrerelease(nextSuffix)
+	if err != nil {
+		return version, fmt.Errorf("failed to set prerelease suffix '%s': %w", nextSuffix, err)
+	}
+
+	return withSuffix, nil
+}
+
+func getNextPrereleaseNumber(prerelease, suffix string) int {
+	pattern := suffix + "."
+	if strings.HasPrefix(prerelease, pattern) {
+		numberStr := strings.TrimPrefix(prerelease, pattern)
+		if number, err := strconv.Atoi(numberStr); err == nil {
+			return number + 1
+		}
+	}
+
+	return 1
+}
+
+// Bump is an increment of a version.
+type Bump int
+
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// MarshalText encodes the bump as its name, e.g. in JSON.
+func (b Bump) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes a bump name, as ParseBump.
+func (b *Bump) UnmarshalText(text []byte) error {
+	bump, err := ParseBump(string(text))
+	if err != nil {
+		return err
+	}
+	*b = bump
+	return nil
+}
+
+// ParseBump parses the name of a bump, as returned by Bump.String. An empty
+// name is BumpNone.
+func ParseBump(name string) (Bump, error) {
+	if name == "" {
+		return BumpNone, nil
+	}
+	for _, b := range []Bump{BumpNone, BumpPatch, BumpMinor, BumpMajor} {
+		if strings.EqualFold(name, b.String()) {
+			return b, nil
+		}
+	}
+	return BumpNone, fmt.Errorf("invalid bump '%s', expected major, minor or patch", name)
+}
+
+// bumpBetween returns the bump leading from one version to the other.
+func bumpBetween(from, to *semver.Version) Bump {
+	switch {
+	case to.Major() != from.Major():
+		return BumpMajor
+	case to.Minor() != from.Minor():
+		return BumpMinor
+	case to.Patch() != from.Patch():
+		return BumpPatch
+	}
+	return BumpNone
+}
+
+func (b Bump) apply(current *semver.Version) semver.Version {
+	switch b {
+	case BumpMajor:
+		return current.IncMajor()
+	case BumpMinor:
+		return current.IncMinor()
+	case BumpPatch:
+		return current.IncPatch()
+	}
+	return *current
+}
+
+// Rules decides which commits bump which part of the version. Breaking
+// changes, marked with "!" or a BREAKING CHANGE footer, always bump major.
+type Rules struct {
+	// MinorTypes are the commit types bumping minor, "feat" by default.
+	MinorTypes []string
+	// PatchTypes are the commit types bumping patch, "fix" by default.
+	PatchTypes []string
+}
+
+// DefaultRules are the rules of Conventional Commits.
+var DefaultRules = Rules{
+	MinorTypes: []string{"feat"},
+	PatchTypes: []string{"fix"},
+}
+
+// classification is what classify read from the commits.
+type classification struct {
+	// bump is the highest bump warranted by the commits, and commit the one
+	// which decided it, the first one warranting that bump.
+	bump   Bump
+	commit *git.Commit
+	// releaseAs is the first commit with a Release-As footer, which forces
+	// the next version instead, and version the value of its footer.
+	releaseAs *git.Commit
+	version   string
+	// breaking are the breaking changes, and approved tells whether a commit
+	// has an Approved-Breaking-Change footer, when all the commits were read.
+	breaking []git.Commit
+	approved bool
+}
+
+// classify reads the commits until it finds a Release-As footer or the first
+// breaking change: a breaking change committed after a Release-As footer
+// supersedes it, and git stops being read as soon as the bump is decided.
+// With all, it reads all the commits instead, for what Policy checks.
+func (r Rules) classify(commits commitSource, all bool) (classification, error) {
+	c := r.classifier()
+	var result classification
+	for commit, err := range commits.commits() {
+		if err != nil {
+			return classification{}, err
+		}
+		if all {
+			if isBreaking(commit) {
+				result.breaking = append(result.breaking, commit)
+			}
+			if approvalLine.MatchString(commit.Body) {
+				_, ok := parseCommit(commit).footer(approvalToken)
+				result.approved = result.approved || ok
+			}
+		}
+		if result.releaseAs != nil {
+			continue
+		}
+		if result.bump < BumpMajor && releaseAsLine.MatchString(commit.Body) {
+			if value, ok := parseCommit(commit).footer(releaseAsToken); ok {
+				result.releaseAs, result.version = &commit, value
+				if !all {
+					break
+				}
+				continue
+			}
+		}
+		if b := c.bump(commit); b > result.bump {
+			result.bump, result.commit = b, &commit
+			if result.bump == BumpMajor && !all {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// classifier classifies commits following Rules.
+type classifier struct {
+	isFeature func(git.Commit) bool
+	isPatch   func(git.Commit) bool
+}
+
+func (r Rules) classifier() classifier {
+	c := classifier{isFeature: isFeature, isPatch: isPatch}
+	if len(r.MinorTypes) > 0 {
+		c.isFeature = typeMatcher(r.MinorTypes)
+	}
+	if len(r.PatchTypes) > 0 {
+		c.isPatch = typeMatcher(r.PatchTypes)
+	}
+	return c
+}
+
+// bump returns the bump warranted by a single commit.
+func (c classifier) bump(commit git.Commit) Bump {
+	switch {
+	case isBreaking(commit):
+		return BumpMajor
+	case c.isFeature(commit):
+		return BumpMinor
+	case c.isPatch(commit):
+		return BumpPatch
+	}
+	return BumpNone
+}
+
+// Override tells what forced the next version, when it wasn't decided by
+// classifying commits.
+type Override string
+
+const (
+	OverrideNone      Override = ""
+	OverrideReleaseAs Override = "release-as"
+	OverrideBump      Override = "bump"
+	OverrideTrailer   Override = "Release-As trailer"
+)
+
+// releaseAsToken is the commit footer forcing the next version.
+const releaseAsToken = "Release-As"
+
+// releaseAsLine matches the lines of commit messages which may be a
+// Release-As footer, sparing the parsing of the other messages.
+var releaseAsLine = regexp.MustCompile(`(?im)^` + releaseAsToken + `(: | #)`)
+
+// decision is how the next version was decided.
+type decision struct {
+	version  semver.Version
+	bump     Bump
+	commit   *git.Commit
+	override Override
+	// classified is what was read from the commits, if anything.
+	classified classification
+}
+
+// decide computes the next version. In order of precedence, it is forced by
+// opts.ReleaseAs, opts.Bump, or the most recent Release-As footer not
+// followed by a breaking change, and only otherwise decided by classifying
+// commits with opts.Rules. The commits are read once, entirely when
+// opts.Policy needs them, even when the version is forced.
+func decide(current *semver.Version, commits commitSource, opts Options) (decision, error) {
+	forced := opts.ReleaseAs != "" || opts.Bump != BumpNone
+	var classified classification
+	if !forced || opts.Policy.readsHistory() {
+		var err error
+		classified, err = opts.Rules.classify(commits, opts.Policy.readsHistory())
+		if err != nil {
+			return decision{}, err
+		}
+	}
+
+	decided := decision{classified: classified}
+	switch {
+	case opts.ReleaseAs != "":
+		version, err := releaseAs(current, opts.ReleaseAs)
+		if err != nil {
+			return decision{}, err
+		}
+		decided.version, decided.bump, decided.override = version, bumpBetween(current, &version), OverrideReleaseAs
+	case opts.Bump != BumpNone:
+		decided.version, decided.bump, decided.override = opts.Bump.apply(current), opts.Bump, OverrideBump
+	case classified.releaseAs != nil:
+		commit := classified.releaseAs
+		version, err := releaseAs(current, classified.version)
+		if err != nil {
+			return decision{}, fmt.Errorf("commit %s: %w", shortSHA(commit.SHA), err)
+		}
+		decided.version, decided.bump, decided.commit, decided.override = version, bumpBetween(current, &version), commit, OverrideTrailer
+	default:
+		decided.version, decided.bump, decided.commit = classified.bump.apply(current), classified.bump, classified.commit
+	}
+	return decided, nil
+}
+
+// releaseAs parses a forced version, which must be greater than current.
+func releaseAs(current *semver.Version, value string) (semver.Version, error) {
+	version, err := semver.StrictNewVersion(strings.TrimSpace(value))
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("invalid release-as version '%s': %w", value, err)
+	}
+	if !version.GreaterThan(current) {
+		return semver.Version{}, fmt.Errorf("release-as version %s must be greater than the current version %s", version, current)
+	}
+	return *version, nil
+}
+
+// typeMatcher returns a function matching commit titles of the given types,
+// the same way feature and patch do for their own.
+func typeMatcher(types []string) func(git.Commit) bool {
+	quoted := make([]string, 0, len(types))
+	for _, t := range types {
+		quoted = append(quoted, regexp.QuoteMeta(strings.TrimSpace(t)))
+	}
+	re := regexp.MustCompile(`(?im).*(?:` + strings.Join(quoted, "|") + `)(\(.*\))?:.*`)
+	return func(commit git.Commit) bool {
+		return re.MatchString(commit.Title)
+	}
+}
+
+func isBreaking(commit git.Commit) bool {
+	return breakingBody.MatchString(commit.Body) || breaking.MatchString(commit.Title)
+}
+
+func isFeature(commit git.Commit) bool {
+	return feature.MatchString(commit.Title)
+}
+
+func isPatch(commit git.Commit) bool {
+	return patch.MatchString(commit.Title)
+}
@@ -0,0 +1,200 @@
+package semtag
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google-internal/semtag/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBreaking(t *testing.T) {
+	for _, commit := range []git.Commit{
+		{Title: "feat!: foo"},
+		{Title: "chore(lala)!: foo"},
+		{Title: "docs: lalala", Body: "BREAKING CHANGE: lalal"},
+		{Title: "docs: lalala", Body: "BREAKING-CHANGE: lalal"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.True(t, isBreaking(commit))
+		})
+	}
+
+	for _, commit := range []git.Commit{
+		{Title: "feat: foo"},
+		{Title: "chore(lol): foo"},
+		{Title: "docs: lalala"},
+		{Title: "docs: BREAKING change: lalal"},
+		{Title: "docs: breaking-change: aehijhk"},
+		{Title: "docs: BREAKING_CHANGE: foo"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.False(t, isBreaking(commit))
+		})
+	}
+}
+
+func TestIsFeature(t *testing.T) {
+	for _, commit := range []git.Commit{
+		{Title: "feat: foo"},
+		{Title: "feat(lalal): foobar"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.True(t, isFeature(commit))
+		})
+	}
+
+	for _, commit := range []git.Commit{
+		{Title: "fix: foo"},
+		{Title: "chore: foo"},
+		{Title: "docs: lalala"},
+		{Title: "ci: foo"},
+		{Title: "test: foo"},
+		{Title: "Merge remote-tracking branch 'origin/main'"},
+		{Title: "refactor: foo bar"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.False(t, isFeature(commit))
+		})
+	}
+}
+
+func TestIsPatch(t *testing.T) {
+	for _, commit := range []git.Commit{
+		{Title: "fix: foo"},
+		{Title: "fix(lalal): lalala"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.True(t, isPatch(commit))
+		})
+	}
+
+	for _, commit := range []git.Commit{
+		{Title: "chore: foobar"},
+		{Title: "docs: something"},
+		{Title: "invalid commit"},
+	} {
+		t.Run(commit.String(), func(t *testing.T) {
+			require.False(t, isPatch(commit))
+		})
+	}
+}
+
+func TestDecideCommits(t *testing.T) {
+	version0a := semver.MustParse("v0.4.5")
+	version0b := semver.MustParse("v0.5.5")
+	version1 := semver.MustParse("v1.2.3")
+	version2 := semver.MustParse("v2.4.12")
+	version3 := semver.MustParse("v3.4.5-beta34+ads")
+
+	for expected, tt := range map[string]struct {
+		current *semver.Version
+		commit  git.Commit
+	}{
+		"0.4.5": {version0a, git.Commit{Title: "chore: should do nothing"}},
+		"0.4.6": {version0a, git.Commit{Title: "fix: inc patch"}},
+		"0.5.0": {version0a, git.Commit{Title: "feat: inc minor"}},
+		"1.0.0": {version0b, git.Commit{Title: "feat!: inc minor"}},
+		"1.2.3": {version1, git.Commit{Title: "chore: should do nothing"}},
+		"1.3.0": {version1, git.Commit{Title: "feat: inc major"}},
+		"2.0.0": {version1, git.Commit{Title: "chore!: hashbang incs major"}},
+		"3.0.0": {version2, git.Commit{Title: "feat: something", Body: "BREAKING CHANGE: increases major"}},
+		"3.5.0": {version3, git.Commit{Title: "feat: inc major"}},
+	} {
+		t.Run(expected, func(t *testing.T) {
+			decided, err := decide(tt.current, commitList{tt.commit}, Options{Rules: DefaultRules})
+			require.NoError(t, err)
+			require.Equal(t, expected, decided.version.String())
+		})
+	}
+}
+
+func TestGetNextPrereleaseNumber(t *testing.T) {
+	t.Run("increments existing number", func(t *testing.T) {
+		require.Equal(t, 3, getNextPrereleaseNumber("beta.2", "beta"))
+	})
+
+	t.Run("falls back to one", func(t *testing.T) {
+		require.Equal(t, 1, getNextPrereleaseNumber("alpha", "alpha"))
+	})
+}
+
+func TestRulesClassify(t *testing.T) {
+	rules := Rules{MinorTypes: []string{"feat", "perf"}, PatchTypes: []string{"fix", "deps"}}
+
+	for expected, commits := range map[Bump][]git.Commit{
+		BumpNone:  {{Title: "chore: foo"}, {Title: "docs: bar"}},
+		BumpPatch: {{Title: "chore: foo"}, {Title: "deps: bump foo"}},
+		BumpMinor: {{Title: "fix: foo"}, {Title: "perf(db): faster"}},
+		BumpMajor: {{Title: "fix: foo"}, {Title: "deps!: drop go 1.22"}},
+	} {
+		t.Run(expected.String(), func(t *testing.T) {
+			classified, err := rules.classify(commitList(commits), false)
+			require.NoError(t, err)
+			require.Equal(t, expected, classified.bump)
+			if expected == BumpNone {
+				require.Nil(t, classified.commit)
+			} else {
+				require.Equal(t, commits[1].Title, classified.commit.Title)
+			}
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	current := semver.MustParse("1.2.3")
+	commits := []git.Commit{
+		{SHA: "aaa", Title: "fix: foo"},
+		{SHA: "bbb", Title: "chore: release", Body: "Release-As: 3.0.0"},
+		{SHA: "ccc", Title: "feat!: bar"},
+	}
+
+	for name, tt := range map[string]struct {
+		opts     Options
+		commits  []git.Commit
+		version  string
+		bump     Bump
+		override Override
+	}{
+		"commits": {commits: commits[2:], version: "2.0.0", bump: BumpMajor},
+		"trailer": {commits: commits, version: "3.0.0", bump: BumpMajor, override: OverrideTrailer},
+		"breaking over an older trailer": {
+			commits: []git.Commit{commits[2], commits[1]}, version: "2.0.0", bump: BumpMajor,
+		},
+		"bump over trailer": {opts: Options{Bump: BumpMinor}, commits: commits, version: "1.3.0", bump: BumpMinor, override: OverrideBump},
+		"bump without commits": {
+			opts: Options{Bump: BumpPatch}, version: "1.2.4", bump: BumpPatch, override: OverrideBump,
+		},
+		"release-as over everything": {
+			opts: Options{ReleaseAs: "1.5.0", Bump: BumpMajor}, commits: commits, version: "1.5.0", bump: BumpMinor, override: OverrideReleaseAs,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			decided, err := decide(current, commitList(tt.commits), tt.opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.version, decided.version.String())
+			require.Equal(t, tt.bump, decided.bump)
+			require.Equal(t, tt.override, decided.override)
+		})
+	}
+
+	t.Run("release-as must be greater", func(t *testing.T) {
+		_, err := decide(current, nil, Options{ReleaseAs: "1.2.3"})
+		require.ErrorContains(t, err, "must be greater than the current version 1.2.3")
+	})
+
+	t.Run("invalid trailer", func(t *testing.T) {
+		_, err := decide(current, commitList{{SHA: "abcdef123", Title: "chore: x", Body: "Release-As: next"}}, Options{})
+		require.ErrorContains(t, err, "commit abcdef1: invalid release-as version 'next'")
+	})
+}
+
+func TestParseBump(t *testing.T) {
+	for name, expected := range map[string]Bump{"": BumpNone, "major": BumpMajor, "Minor": BumpMinor, "patch": BumpPatch} {
+		bump, err := ParseBump(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, bump)
+	}
+	_, err := ParseBump("huge")
+	require.Error(t, err)
+}